@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The client side of the /api/v1 routes of the server. The types only hold the fields snip uses, the OpenAPI
+// document at /api/v1/openapi.json describing the others.
+
+type file struct {
+	Name string `json:"name"`
+	// Language is left out of requests to have it detected.
+	Language *string `json:"language,omitempty"`
+	Content  string  `json:"content"`
+}
+
+type snippet struct {
+	ID             int       `json:"id"`
+	Author         string    `json:"author"`
+	Title          string    `json:"title"`
+	Filename       string    `json:"filename"`
+	Content        string    `json:"content"`
+	Language       string    `json:"language"`
+	Files          []file    `json:"files"`
+	Visibility     string    `json:"visibility"`
+	Tags           []string  `json:"tags"`
+	ViewsRemaining *int      `json:"views_remaining"`
+	Protected      bool      `json:"protected"`
+	Created        time.Time `json:"created"`
+	Expires        time.Time `json:"expires"`
+}
+
+// file returns the file of the snippet with the given name, the first one for the empty name.
+func (s snippet) file(name string) (file, bool) {
+	if name == "" || name == s.Filename {
+		return file{Name: s.Filename, Language: &s.Language, Content: s.Content}, true
+	}
+
+	for _, f := range s.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return file{}, false
+}
+
+type snippetInput struct {
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Language   *string  `json:"language,omitempty"`
+	Filename   string   `json:"filename,omitempty"`
+	Files      []file   `json:"files,omitempty"`
+	Expires    int      `json:"expires"`
+	Visibility string   `json:"visibility"`
+	Tags       []string `json:"tags,omitempty"`
+	MaxViews   int      `json:"max_views,omitempty"`
+	Password   string   `json:"password,omitempty"`
+}
+
+// apiError is an error response of the API.
+type apiError struct {
+	Status         int
+	Message        string            `json:"error"`
+	NonFieldErrors []string          `json:"non_field_errors"`
+	FieldErrors    map[string]string `json:"field_errors"`
+}
+
+// Error tells what went wrong, with the field errors of invalid input one per line.
+func (e *apiError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+
+	for _, message := range e.NonFieldErrors {
+		fmt.Fprintf(&b, "\n  %s", message)
+	}
+
+	fields := make([]string, 0, len(e.FieldErrors))
+	for field := range e.FieldErrors {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "\n  %s: %s", field, e.FieldErrors[field])
+	}
+
+	return b.String()
+}
+
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func newClient(c config) (*client, error) {
+	if c.Server == "" {
+		return nil, errors.New("no server configured, set one with: snip config server URL")
+	}
+
+	return &client{server: c.Server, token: c.Token, http: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// do sends a request to the API, with the token when there is one, and decodes the response into dst, unless it's
+// nil. Responses other than 2xx come back as an *apiError.
+func (c *client) do(method, path string, header http.Header, body, dst any) error {
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, c.server+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := &apiError{Status: res.StatusCode}
+		err = json.NewDecoder(res.Body).Decode(apiErr)
+		if err != nil || apiErr.Message == "" {
+			apiErr.Message = fmt.Sprintf("the server answered %s", res.Status)
+		}
+		return apiErr
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(res.Body).Decode(dst)
+	if err != nil {
+		return fmt.Errorf("invalid response from the server: %w", err)
+	}
+
+	return nil
+}
+
+// createSnippet creates a snippet, returning the address of its page.
+func (c *client) createSnippet(input snippetInput) (string, error) {
+	var response struct {
+		URL string `json:"url"`
+	}
+
+	err := c.do(http.MethodPost, "/snippets", nil, input, &response)
+	return response.URL, err
+}
+
+// snippet gets a snippet, sending the passphrase of protected snippets when it isn't empty. The raw JSON of the
+// snippet comes along, for printing it as the server sent it.
+func (c *client) snippet(id int, passphrase string) (snippet, json.RawMessage, error) {
+	header := http.Header{}
+	if passphrase != "" {
+		header.Set("X-Snippet-Passphrase", passphrase)
+	}
+
+	var response struct {
+		Snippet json.RawMessage `json:"snippet"`
+	}
+
+	err := c.do(http.MethodGet, fmt.Sprintf("/snippets/%d", id), header, nil, &response)
+	if err != nil {
+		return snippet{}, nil, err
+	}
+
+	var s snippet
+	err = json.Unmarshal(response.Snippet, &s)
+	if err != nil {
+		return snippet{}, nil, fmt.Errorf("invalid response from the server: %w", err)
+	}
+
+	return s, response.Snippet, nil
+}
+
+// userSnippets lists the snippets of the owner of the token, along with the raw JSON of the list. The server sends
+// them a page at a time, the pages are followed until the last one.
+func (c *client) userSnippets() ([]snippet, json.RawMessage, error) {
+	var snippets []snippet
+	raw := []json.RawMessage{}
+
+	path := "/user/snippets"
+	for {
+		var response struct {
+			Snippets []json.RawMessage `json:"snippets"`
+			Next     *string           `json:"next"`
+		}
+
+		err := c.do(http.MethodGet, path, nil, nil, &response)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, js := range response.Snippets {
+			var s snippet
+			err = json.Unmarshal(js, &s)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid response from the server: %w", err)
+			}
+			snippets = append(snippets, s)
+		}
+		raw = append(raw, response.Snippets...)
+
+		if response.Next == nil {
+			break
+		}
+		path = "/user/snippets?after=" + url.QueryEscape(*response.Next)
+	}
+
+	js, err := json.Marshal(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return snippets, js, nil
+}
+
+func (c *client) deleteSnippet(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/snippets/%d", id), nil, nil, nil)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultTitle is the title of the snippets created from stdin, the same as the pastes of the server.
+const defaultTitle = "Untitled paste"
+
+// flags returns the flag set of a command, with a usage made of its arguments and description.
+func (c *cli) flags(name, args, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: snip %s %s\n\n%s\n", name, args, description)
+		if hasFlags(fs) {
+			fmt.Fprintf(c.stderr, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// parse parses the flags of a command. The flag package has already printed what was wrong, so the error is errUsage
+// alone, apart from the -h flag.
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// client returns a client of the configured server. Commands acting on the snippets of the user need a token.
+func (c *cli) client(needToken bool) (*client, error) {
+	if needToken && c.config.Token == "" {
+		return nil, errors.New("no token configured, create one on the API tokens page of your account and set it with: snip config token -")
+	}
+	return newClient(c.config)
+}
+
+func parseID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return 0, usageErrorf("invalid snippet ID %q", arg)
+	}
+	return id, nil
+}
+
+// singleID parses the flags of a command taking a single snippet ID, returning it.
+func singleID(fs *flag.FlagSet, args []string) (int, error) {
+	err := parse(fs, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if fs.NArg() != 1 {
+		return 0, usageErrorf("%s takes a single snippet ID", fs.Name())
+	}
+
+	return parseID(fs.Arg(0))
+}
+
+// create creates a snippet from the files, the first one being the snippet and the others its extra files, or from
+// stdin without files. It prints the address of the page of the snippet.
+func (c *cli) create(args []string) error {
+	fs := c.flags("create", "[flags] [file ...]", "Create a snippet from the files, or from stdin when there are none, and print its address.")
+	title := fs.String("title", "", "title of the snippet, the name of the first file by default")
+	filename := fs.String("filename", "", "name of the snippet read from stdin")
+	language := fs.String("language", "auto", "language of the first file, detected by default, empty for plain text")
+	expires := fs.Int("expires", 7, "lifetime of the snippet in days: 1, 7 or 365")
+	visibility := fs.String("visibility", "unlisted", "visibility of the snippet: public, unlisted or private")
+	tags := fs.String("tags", "", "comma-separated tags of the snippet")
+	maxViews := fs.Int("max-views", 0, "number of views after which the snippet is deleted, 0 for no limit")
+	password := fs.String("password", "", "passphrase needed to read the snippet")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	input := snippetInput{
+		Title:      *title,
+		Language:   language,
+		Expires:    *expires,
+		Visibility: *visibility,
+		MaxViews:   *maxViews,
+		Password:   *password,
+	}
+
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			input.Tags = append(input.Tags, tag)
+		}
+	}
+
+	if fs.NArg() == 0 {
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		input.Content = string(content)
+		input.Filename = *filename
+	} else {
+		for i, path := range fs.Args() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			if i == 0 {
+				input.Filename = filepath.Base(path)
+				input.Content = string(content)
+				continue
+			}
+			input.Files = append(input.Files, file{Name: filepath.Base(path), Content: string(content)})
+		}
+	}
+
+	if strings.TrimSpace(input.Content) == "" {
+		return errors.New("the snippet is empty")
+	}
+
+	if input.Title == "" {
+		input.Title = defaultTitle
+		if input.Filename != "" {
+			input.Title = input.Filename
+		}
+	}
+
+	client, err := c.client(true)
+	if err != nil {
+		return err
+	}
+
+	url, err := client.createSnippet(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout, url)
+	return nil
+}
+
+// get prints a snippet: its title and details, then its files.
+func (c *cli) get(args []string) error {
+	fs := c.flags("get", "[flags] id", "Print a snippet with its details. Snippets with a view limit lose a view.")
+	asJSON := fs.Bool("json", false, "print the snippet as the API sends it")
+	passphrase := fs.String("passphrase", "", "passphrase of a protected snippet")
+
+	id, err := singleID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client(false)
+	if err != nil {
+		return err
+	}
+
+	s, raw, err := client.snippet(id, *passphrase)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(c.stdout, raw)
+	}
+
+	details := []string{fmt.Sprintf("#%d by %s", s.ID, s.Author), s.Visibility, languageName(s.Language)}
+	if s.Protected {
+		details = append(details, "protected")
+	}
+	details = append(details, "expires "+humanDate(s.Expires))
+
+	fmt.Fprintln(c.stdout, s.Title)
+	fmt.Fprintln(c.stdout, strings.Join(details, ", "))
+	if len(s.Tags) > 0 {
+		fmt.Fprintf(c.stdout, "tags: %s\n", strings.Join(s.Tags, ", "))
+	}
+	if s.ViewsRemaining != nil {
+		fmt.Fprintf(c.stdout, "views remaining: %d\n", *s.ViewsRemaining)
+	}
+	fmt.Fprintf(c.stdout, "%s/snippet/view/%d\n", client.server, s.ID)
+
+	first, _ := s.file("")
+	for _, f := range append([]file{first}, s.Files...) {
+		fmt.Fprintln(c.stdout)
+		// Files only get a header when there is a name to put in it.
+		if f.Name != "" {
+			fmt.Fprintf(c.stdout, "--- %s ---\n", f.Name)
+		}
+		fmt.Fprint(c.stdout, f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			fmt.Fprintln(c.stdout)
+		}
+	}
+
+	return nil
+}
+
+// raw prints the content of a file of a snippet as it is, for redirecting it to a file or piping it.
+func (c *cli) raw(args []string) error {
+	fs := c.flags("raw", "[flags] id", "Print the raw content of a snippet, its first file by default. Snippets with a view limit lose a view.")
+	name := fs.String("file", "", "name of the file to print")
+	passphrase := fs.String("passphrase", "", "passphrase of a protected snippet")
+
+	id, err := singleID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client(false)
+	if err != nil {
+		return err
+	}
+
+	s, _, err := client.snippet(id, *passphrase)
+	if err != nil {
+		return err
+	}
+
+	f, ok := s.file(*name)
+	if !ok {
+		return fmt.Errorf("snippet %d has no file named %q", id, *name)
+	}
+
+	_, err = io.WriteString(c.stdout, f.Content)
+	return err
+}
+
+// list prints the snippets of the owner of the token, one per line.
+func (c *cli) list(args []string) error {
+	fs := c.flags("list", "[flags]", "List your snippets, the newest first.")
+	asJSON := fs.Bool("json", false, "print the snippets as the API sends them")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("list doesn't take arguments")
+	}
+
+	client, err := c.client(true)
+	if err != nil {
+		return err
+	}
+
+	snippets, raw, err := client.userSnippets()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(c.stdout, raw)
+	}
+
+	if len(snippets) == 0 {
+		fmt.Fprintln(c.stdout, "You have no snippets.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tVISIBILITY\tEXPIRES\tTITLE")
+	for _, s := range snippets {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.ID, s.Visibility, humanDate(s.Expires), s.Title)
+	}
+
+	return tw.Flush()
+}
+
+// delete deletes the snippets, stopping at the first one that can't be.
+func (c *cli) delete(args []string) error {
+	fs := c.flags("delete", "id ...", "Delete snippets.")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("delete takes the IDs of the snippets to delete")
+	}
+
+	ids := make([]int, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	client, err := c.client(true)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err = client.deleteSnippet(id)
+		if err != nil {
+			return fmt.Errorf("snippet %d: %w", id, err)
+		}
+		fmt.Fprintf(c.stdout, "Deleted snippet %d.\n", id)
+	}
+
+	return nil
+}
+
+// configure shows the config without arguments, a key of it with one, and changes a key with two. A value of "-" is
+// read from stdin.
+func (c *cli) configure(args []string) error {
+	fs := c.flags("config", "[key [value]]", "Show or change the config: the server URL and the API token. A value of - is read from stdin.")
+
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	switch fs.NArg() {
+	case 0:
+		for _, key := range configKeys {
+			value, _ := c.config.get(key)
+			switch {
+			case value == "":
+				value = "(not set)"
+			case key == "token":
+				value = maskToken(value)
+			}
+			fmt.Fprintf(c.stdout, "%s = %s\n", key, value)
+		}
+		return nil
+	case 1:
+		value, err := c.config.get(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.stdout, value)
+		return nil
+	case 2:
+		value := fs.Arg(1)
+		if value == "-" {
+			value, err = bufio.NewReader(c.stdin).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+
+		err = c.config.set(fs.Arg(0), value)
+		if err != nil {
+			return err
+		}
+		return c.config.save(c.configPath)
+	}
+
+	return usageErrorf("config takes a key and a value at most")
+}
+
+// printJSON prints JSON as the server sent it, indented like the server does.
+func printJSON(w io.Writer, raw json.RawMessage) error {
+	var buf bytes.Buffer
+
+	err := json.Indent(&buf, raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func languageName(language string) string {
+	if language == "" {
+		return "plain text"
+	}
+	return language
+}
+
+// humanDate formats a date like the pages of the server do.
+func humanDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("02 Jan 2006 at 15:04")
+}
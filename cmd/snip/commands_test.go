@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+const testToken = "MOCKTOKENFORTHEMOCKUSER"
+
+const testSnippet = `{
+	"id": 1,
+	"user_id": 1,
+	"author": "alice",
+	"title": "An old silent pond",
+	"filename": "haiku.txt",
+	"content": "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+	"language": "",
+	"files": [{"name": "notes.md", "language": "markdown", "content": "# Basho\n"}],
+	"visibility": "public",
+	"tags": ["haiku"],
+	"views_remaining": null,
+	"protected": false,
+	"forked_from": null,
+	"created": "2026-10-01T10:00:00Z",
+	"expires": "2026-10-08T10:00:00Z"
+}`
+
+// fakeAPI is a server answering like the /api/v1 routes of the web application, recording the snippets created.
+type fakeAPI struct {
+	*httptest.Server
+	created []snippetInput
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{}
+
+	writeJSON := func(w http.ResponseWriter, status int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+	authenticated := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			writeJSON(w, http.StatusUnauthorized, `{"error": "invalid or missing authentication token"}`)
+			return false
+		}
+		return true
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/v1/snippets", func(w http.ResponseWriter, r *http.Request) {
+		if !authenticated(w, r) {
+			return
+		}
+
+		var input snippetInput
+		err := json.NewDecoder(r.Body).Decode(&input)
+		if err != nil {
+			t.Error(err)
+			writeJSON(w, http.StatusBadRequest, `{"error": "body contains badly-formed JSON"}`)
+			return
+		}
+
+		if input.Expires == 3 {
+			writeJSON(w, http.StatusUnprocessableEntity, `{"error": "the request contains invalid fields", "field_errors": {"expires": "This field must equal to 1, 7 or 365"}}`)
+			return
+		}
+
+		api.created = append(api.created, input)
+		writeJSON(w, http.StatusCreated, `{"id": 2, "url": "`+api.URL+`/snippet/view/2"}`)
+	})
+
+	mux.HandleFunc("GET /api/v1/snippets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "1":
+			writeJSON(w, http.StatusOK, `{"snippet": `+testSnippet+`}`)
+		case "6":
+			if r.Header.Get("X-Snippet-Passphrase") != "open sesame" {
+				writeJSON(w, http.StatusForbidden, `{"error": "the passphrase is incorrect"}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, `{"snippet": `+strings.Replace(testSnippet, `"protected": false`, `"protected": true`, 1)+`}`)
+		default:
+			writeJSON(w, http.StatusNotFound, `{"error": "the requested resource could not be found"}`)
+		}
+	})
+
+	// The snippets of the user come on two pages.
+	mux.HandleFunc("GET /api/v1/user/snippets", func(w http.ResponseWriter, r *http.Request) {
+		if !authenticated(w, r) {
+			return
+		}
+		if r.URL.Query().Get("after") == "cursor/1" {
+			second := strings.NewReplacer(`"id": 1`, `"id": 7`, `"An old silent pond"`, `"Over the wintry forest"`).Replace(testSnippet)
+			writeJSON(w, http.StatusOK, `{"snippets": [`+second+`], "next": null, "prev": "cursor/7"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"snippets": [`+testSnippet+`], "next": "cursor/1", "prev": null}`)
+	})
+
+	mux.HandleFunc("DELETE /api/v1/snippets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !authenticated(w, r) {
+			return
+		}
+		if r.PathValue("id") != "1" {
+			writeJSON(w, http.StatusNotFound, `{"error": "the requested resource could not be found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	api.Server = httptest.NewServer(mux)
+
+	return api
+}
+
+// writeConfig writes a config file in a temporary directory, returning its path.
+func writeConfig(t *testing.T, c config) string {
+	path := filepath.Join(t.TempDir(), ".snip")
+
+	err := c.save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// runSnip runs snip with the arguments, returning what it printed.
+func runSnip(t *testing.T, configPath, stdin string, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	err := run(args, configPath, strings.NewReader(stdin), &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), err
+}
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".snip")
+
+	stdout, _, err := runSnip(t, path, "", "config")
+	assert.NilError(t, err)
+	assert.Equal(t, stdout, "server = (not set)\ntoken = (not set)\n")
+
+	_, _, err = runSnip(t, path, "", "config", "server", "https://snippetbox.example.com/")
+	assert.NilError(t, err)
+
+	_, _, err = runSnip(t, path, testToken+"\n", "config", "token", "-")
+	assert.NilError(t, err)
+
+	stdout, _, err = runSnip(t, path, "", "config")
+	assert.NilError(t, err)
+	assert.Equal(t, stdout, "server = https://snippetbox.example.com\ntoken = *******************USER\n")
+
+	stdout, _, err = runSnip(t, path, "", "config", "token")
+	assert.NilError(t, err)
+	assert.Equal(t, stdout, testToken+"\n")
+
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	t.Run("Invalid server", func(t *testing.T) {
+		_, _, err := runSnip(t, path, "", "config", "server", "snippetbox.example.com")
+		assert.StringContains(t, err.Error(), "invalid server")
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		_, _, err := runSnip(t, path, "", "config", "user", "alice")
+		assert.StringContains(t, err.Error(), "unknown config key")
+	})
+}
+
+func TestCreate(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	path := writeConfig(t, config{Server: api.URL, Token: testToken})
+
+	t.Run("From stdin", func(t *testing.T) {
+		stdout, _, err := runSnip(t, path, "package main\n", "create", "-tags", "go, cli", "-filename", "main.go")
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, api.URL+"/snippet/view/2\n")
+
+		input := api.created[len(api.created)-1]
+		assert.Equal(t, input.Title, "main.go")
+		assert.Equal(t, input.Filename, "main.go")
+		assert.Equal(t, input.Content, "package main\n")
+		assert.Equal(t, *input.Language, "auto")
+		assert.Equal(t, input.Visibility, "unlisted")
+		assert.Equal(t, input.Expires, 7)
+		assert.Equal(t, strings.Join(input.Tags, ","), "go,cli")
+	})
+
+	t.Run("From files", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range map[string]string{"main.go": "package main\n", "go.mod": "module snip\n"} {
+			err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, _, err := runSnip(t, path, "", "create", "-title", "A module", filepath.Join(dir, "main.go"), filepath.Join(dir, "go.mod"))
+		assert.NilError(t, err)
+
+		input := api.created[len(api.created)-1]
+		assert.Equal(t, input.Title, "A module")
+		assert.Equal(t, input.Filename, "main.go")
+		assert.Equal(t, len(input.Files), 1)
+		assert.Equal(t, input.Files[0].Name, "go.mod")
+		assert.Equal(t, input.Files[0].Content, "module snip\n")
+		assert.Equal(t, input.Files[0].Language == nil, true)
+	})
+
+	t.Run("Untitled", func(t *testing.T) {
+		_, _, err := runSnip(t, path, "echo hello\n", "create")
+		assert.NilError(t, err)
+		assert.Equal(t, api.created[len(api.created)-1].Title, defaultTitle)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		_, _, err := runSnip(t, path, "  \n", "create")
+		assert.StringContains(t, err.Error(), "the snippet is empty")
+	})
+
+	t.Run("Invalid fields", func(t *testing.T) {
+		_, _, err := runSnip(t, path, "package main\n", "create", "-expires", "3")
+
+		var apiErr *apiError
+		assert.Equal(t, errors.As(err, &apiErr), true)
+		assert.Equal(t, apiErr.Status, http.StatusUnprocessableEntity)
+		assert.StringContains(t, err.Error(), "\n  expires: This field must equal to 1, 7 or 365")
+	})
+
+	t.Run("No token", func(t *testing.T) {
+		path := writeConfig(t, config{Server: api.URL})
+
+		_, _, err := runSnip(t, path, "package main\n", "create")
+		assert.StringContains(t, err.Error(), "no token configured")
+	})
+
+	t.Run("No server", func(t *testing.T) {
+		path := writeConfig(t, config{Token: testToken})
+
+		_, _, err := runSnip(t, path, "package main\n", "create")
+		assert.StringContains(t, err.Error(), "no server configured")
+	})
+}
+
+func TestGetRaw(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	path := writeConfig(t, config{Server: api.URL})
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantOutput string
+		wantError  string
+	}{
+		{
+			name: "Get",
+			args: []string{"get", "1"},
+			wantOutput: "An old silent pond\n" +
+				"#1 by alice, public, plain text, expires 08 Oct 2026 at 10:00\n" +
+				"tags: haiku\n" +
+				api.URL + "/snippet/view/1\n" +
+				"\n--- haiku.txt ---\nAn old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.\n" +
+				"\n--- notes.md ---\n# Basho\n",
+		},
+		{
+			name:       "Raw",
+			args:       []string{"raw", "1"},
+			wantOutput: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+		},
+		{
+			name:       "Raw file",
+			args:       []string{"raw", "-file", "notes.md", "1"},
+			wantOutput: "# Basho\n",
+		},
+		{
+			name:      "Raw unknown file",
+			args:      []string{"raw", "-file", "README.md", "1"},
+			wantError: `snippet 1 has no file named "README.md"`,
+		},
+		{
+			name:       "Protected with the passphrase",
+			args:       []string{"raw", "-passphrase", "open sesame", "6"},
+			wantOutput: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+		},
+		{
+			name:      "Protected without passphrase",
+			args:      []string{"get", "6"},
+			wantError: "the passphrase is incorrect",
+		},
+		{
+			name:      "Non-existent snippet",
+			args:      []string{"get", "2"},
+			wantError: "the requested resource could not be found",
+		},
+		{
+			name:      "Invalid ID",
+			args:      []string{"get", "foo"},
+			wantError: `usage: invalid snippet ID "foo"`,
+		},
+		{
+			name:      "Several IDs",
+			args:      []string{"raw", "1", "2"},
+			wantError: "usage: raw takes a single snippet ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, err := runSnip(t, path, "", tt.args...)
+
+			if tt.wantError != "" {
+				if err == nil {
+					t.Fatalf("got no error; want %q", tt.wantError)
+				}
+				assert.Equal(t, err.Error(), tt.wantError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, stdout, tt.wantOutput)
+		})
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		stdout, _, err := runSnip(t, path, "", "get", "-json", "1")
+		assert.NilError(t, err)
+
+		var s map[string]any
+		assert.NilError(t, json.Unmarshal([]byte(stdout), &s))
+		assert.Equal(t, s["title"], any("An old silent pond"))
+		assert.Equal(t, s["user_id"], any(float64(1)))
+	})
+}
+
+func TestList(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	t.Run("Table", func(t *testing.T) {
+		stdout, _, err := runSnip(t, writeConfig(t, config{Server: api.URL, Token: testToken}), "", "list")
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, "ID  VISIBILITY  EXPIRES               TITLE\n1   public      08 Oct 2026 at 10:00  An old silent pond\n7   public      08 Oct 2026 at 10:00  Over the wintry forest\n")
+	})
+
+	t.Run("JSON of every page", func(t *testing.T) {
+		stdout, _, err := runSnip(t, writeConfig(t, config{Server: api.URL, Token: testToken}), "", "list", "-json")
+		assert.NilError(t, err)
+		assert.Equal(t, strings.HasPrefix(stdout, "[\n\t{"), true)
+		assert.Equal(t, strings.Count(stdout, "\"author\": \"alice\""), 2)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		_, _, err := runSnip(t, writeConfig(t, config{Server: api.URL, Token: "NOTATOKEN"}), "", "list")
+		assert.Equal(t, err.Error(), "invalid or missing authentication token")
+	})
+}
+
+func TestDelete(t *testing.T) {
+	api := newFakeAPI(t)
+	defer api.Close()
+
+	path := writeConfig(t, config{Server: api.URL, Token: testToken})
+
+	stdout, _, err := runSnip(t, path, "", "delete", "1")
+	assert.NilError(t, err)
+	assert.Equal(t, stdout, "Deleted snippet 1.\n")
+
+	stdout, _, err = runSnip(t, path, "", "delete", "1", "2", "1")
+	assert.Equal(t, stdout, "Deleted snippet 1.\n")
+	assert.Equal(t, err.Error(), "snippet 2: the requested resource could not be found")
+
+	_, _, err = runSnip(t, path, "", "delete")
+	assert.Equal(t, errors.Is(err, errUsage), true)
+}
+
+func TestUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".snip")
+
+	_, stderr, err := runSnip(t, path, "")
+	assert.Equal(t, err, errUsage)
+	assert.StringContains(t, stderr, "Usage: snip <command> [arguments]")
+
+	stdout, _, err := runSnip(t, path, "", "help")
+	assert.NilError(t, err)
+	assert.StringContains(t, stdout, "create [flags] [file ...]")
+
+	_, _, err = runSnip(t, path, "", "paste")
+	assert.Equal(t, errors.Is(err, errUsage), true)
+
+	_, stderr, err = runSnip(t, path, "", "create", "-private")
+	assert.Equal(t, err, errUsage)
+	assert.StringContains(t, stderr, "flag provided but not defined: -private")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config is what snip keeps between runs, in the JSON dotfile at configPath.
+type config struct {
+	// Server is the address of the Snippetbox server, like https://snippetbox.example.com.
+	Server string `json:"server,omitempty"`
+	// Token is an API token, created on the API tokens page of the account.
+	Token string `json:"token,omitempty"`
+}
+
+// configKeys are the keys of the config command, in the order it lists them.
+var configKeys = []string{"server", "token"}
+
+// defaultConfigPath returns where the config is kept: the file named by SNIP_CONFIG, or ~/.snip.
+func defaultConfigPath() (string, error) {
+	if path := os.Getenv("SNIP_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".snip"), nil
+}
+
+// loadConfig reads the config file. A missing file is an empty config, since nothing has been set yet.
+func loadConfig(path string) (config, error) {
+	var c config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return c, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// save writes the config file, readable by its owner only since it holds the token.
+func (c config) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0600)
+	if err != nil {
+		return err
+	}
+
+	// WriteFile keeps the permissions of an existing file.
+	return os.Chmod(path, 0600)
+}
+
+func (c config) get(key string) (string, error) {
+	switch key {
+	case "server":
+		return c.Server, nil
+	case "token":
+		return c.Token, nil
+	}
+	return "", fmt.Errorf("unknown config key %q, want one of %s", key, strings.Join(configKeys, ", "))
+}
+
+// set changes a key of the config. The server must be an http or https URL, which is kept without its trailing slash
+// so that the API paths can be appended to it.
+func (c *config) set(key, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "server":
+		if value != "" {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid server %q, want an http or https URL", value)
+			}
+			value = strings.TrimSuffix(value, "/")
+		}
+		c.Server = value
+	case "token":
+		c.Token = value
+	default:
+		return fmt.Errorf("unknown config key %q, want one of %s", key, strings.Join(configKeys, ", "))
+	}
+
+	return nil
+}
+
+// maskToken hides all of a token but its last characters, enough to tell tokens apart.
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", len(token)-4) + token[len(token)-4:]
+}
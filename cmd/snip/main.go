@@ -0,0 +1,107 @@
+// Command snip is a command-line client of Snippetbox. It creates snippets from files or from its standard input,
+// prints them, lists and deletes the snippets of the user, through the JSON API of the server.
+//
+// The address of the server and an API token are kept in a dotfile, set with the config command:
+//
+//	snip config server https://snippetbox.example.com
+//	snip config token -   # reads the token from stdin, keeping it out of the shell history
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errUsage is returned when snip is used wrongly. The usage has already been printed when it comes alone.
+var errUsage = errors.New("usage")
+
+// usageErrorf reports a wrong use of a command.
+func usageErrorf(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{errUsage}, args...)...)
+}
+
+func main() {
+	configPath, err := defaultConfigPath()
+	if err == nil {
+		err = run(os.Args[1:], configPath, os.Stdin, os.Stdout, os.Stderr)
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case err == errUsage:
+		os.Exit(2)
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "snip: %s\n", err)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "snip: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// cli is a run of snip: the config it loaded, and where it reads and writes.
+type cli struct {
+	configPath string
+	config     config
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+// command is a command of snip. Args describes its arguments in the usage.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"create", "[flags] [file ...]", "create a snippet from the files, or from stdin", (*cli).create},
+	{"get", "[flags] id", "print a snippet with its details", (*cli).get},
+	{"raw", "[flags] id", "print the raw content of a snippet", (*cli).raw},
+	{"list", "[flags]", "list your snippets", (*cli).list},
+	{"delete", "id ...", "delete snippets", (*cli).delete},
+	{"config", "[key [value]]", "show or change the config: server and token", (*cli).configure},
+}
+
+func run(args []string, configPath string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := &cli{configPath: configPath, stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		c.usage(stderr)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		c.usage(stdout)
+		return nil
+	}
+
+	var err error
+	c.config, err = loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(c, args[1:])
+		}
+	}
+
+	return usageErrorf("unknown command %q, run 'snip help' for the list of commands", args[0])
+}
+
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: snip <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-28s %s\n", cmd.name+" "+cmd.args, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'snip <command> -h' for the flags of a command.\n")
+	fmt.Fprintf(w, "The config is kept in %s, or the file named by SNIP_CONFIG.\n", c.configPath)
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.hichammou/internal/models"
+	"snippetbox.hichammou/internal/validator"
+)
+
+// The handlers of the /api/v1 routes. They work on the same models as the HTML handlers, and reuse their forms for
+// validation, but they speak JSON: responses are wrapped in an object named after what they hold, and errors come
+// as {"error": "..."}, with the field errors of the validator for invalid input. Every body is one of the types
+// below, which the OpenAPI document describes.
+
+// apiPageSize is the number of snippets of a page of the API listings.
+const apiPageSize = 50
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type apiValidationResponse struct {
+	Error string `json:"error"`
+	validator.Validator
+}
+
+type apiSnippetResponse struct {
+	Snippet apiSnippet `json:"snippet"`
+}
+
+// apiSnippetsResponse is a list of snippets. The cursors of the neighbouring pages are null when there is no such
+// page, or when the list isn't paginated. A listing never takes a passphrase, so the content and files of protected
+// snippets are left empty.
+type apiSnippetsResponse struct {
+	Snippets []apiSnippet `json:"snippets"`
+	Next     *string      `json:"next"`
+	Prev     *string      `json:"prev"`
+}
+
+// apiSavedResponse answers the creation or update of a snippet with its ID and the address of its page.
+type apiSavedResponse struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+}
+
+// writeJSON sends data as the JSON response body with the given status.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data any) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(js, '\n'))
+
+	return nil
+}
+
+// readJSON decodes the request body into dst. The body must hold a single JSON value, without fields dst doesn't know
+// about, so that a typo in a field name is reported instead of being silently ignored.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	// Room for every file at its largest, plus the JSON around them.
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxSnippetSize)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			return fmt.Errorf("body contains the wrong type for the %q field", typeError.Field)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// apiError sends an error response. Server errors are logged and hidden behind a generic message.
+func (app *application) apiError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	err := app.writeJSON(w, status, apiErrorResponse{Error: message})
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+	app.apiError(w, r, http.StatusInternalServerError, "the server encountered a problem and could not process the request")
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter, r *http.Request) {
+	app.apiError(w, r, http.StatusNotFound, "the requested resource could not be found")
+}
+
+// apiValidationError sends the errors of an invalid form, with its field errors renamed after the JSON fields.
+func (app *application) apiValidationError(w http.ResponseWriter, r *http.Request, v validator.Validator) {
+	fieldErrors := make(map[string]string, len(v.FieldErrors))
+	for key, message := range v.FieldErrors {
+		fieldErrors[apiFieldName(key)] = message
+	}
+	v.FieldErrors = fieldErrors
+
+	response := apiValidationResponse{
+		Error:     "the request contains invalid fields",
+		Validator: v,
+	}
+
+	err := app.writeJSON(w, http.StatusUnprocessableEntity, response)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiFieldName returns the JSON name of a field of the snippet form: "maxViews" is "max_views", and the errors of the
+// files, "file_<index>" in the form, are "files.<index>".
+func apiFieldName(key string) string {
+	if key == "maxViews" {
+		return "max_views"
+	}
+	if index, ok := strings.CutPrefix(key, "file_"); ok {
+		return "files." + index
+	}
+	return key
+}
+
+// apiFile is a file of a snippet as the API sends and receives it.
+type apiFile struct {
+	Name string `json:"name"`
+	// Language is only a pointer in requests, where leaving it out asks for the language to be detected.
+	Language *string `json:"language" openapi:"optional"`
+	Content  string  `json:"content"`
+}
+
+// apiSnippet is a snippet as the API sends it. Pointers are null when the snippet doesn't have the value.
+type apiSnippet struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	Author         string    `json:"author"`
+	Title          string    `json:"title"`
+	Filename       string    `json:"filename"`
+	Content        string    `json:"content"`
+	Language       string    `json:"language"`
+	Files          []apiFile `json:"files"`
+	Visibility     string    `json:"visibility" enum:"public,unlisted,private"`
+	Tags           []string  `json:"tags"`
+	ViewsRemaining *int      `json:"views_remaining"`
+	Protected      bool      `json:"protected"`
+	ForkedFrom     *int      `json:"forked_from"`
+	Created        time.Time `json:"created"`
+	Expires        time.Time `json:"expires"`
+}
+
+func newAPISnippet(s models.Snippet) apiSnippet {
+	snippet := apiSnippet{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		Author:     s.UserName,
+		Title:      s.Title,
+		Filename:   s.Filename,
+		Content:    s.Content,
+		Language:   s.Language,
+		Files:      []apiFile{},
+		Visibility: s.Visibility,
+		Tags:       s.Tags,
+		Protected:  s.IsProtected(),
+		Created:    s.Created,
+		Expires:    s.Expires,
+	}
+
+	if snippet.Tags == nil {
+		snippet.Tags = []string{}
+	}
+	for _, f := range s.Files {
+		snippet.Files = append(snippet.Files, apiFile{Name: f.Name, Language: &f.Language, Content: f.Content})
+	}
+	if s.ViewsRemaining.Valid {
+		views := int(s.ViewsRemaining.Int32)
+		snippet.ViewsRemaining = &views
+	}
+	if s.ForkedFrom.Valid {
+		forkedFrom := int(s.ForkedFrom.Int32)
+		snippet.ForkedFrom = &forkedFrom
+	}
+
+	return snippet
+}
+
+// newAPISnippetsPage converts a page of snippets, along with the encoded cursors of its neighbours.
+func newAPISnippetsPage(page models.Page) apiSnippetsResponse {
+	response := apiSnippetsResponse{Snippets: newAPISnippets(page.Snippets)}
+	for i := range response.Snippets {
+		if response.Snippets[i].Protected {
+			response.Snippets[i].Content = ""
+			response.Snippets[i].Files = []apiFile{}
+		}
+	}
+	if page.Next != nil {
+		next := page.Next.Encode()
+		response.Next = &next
+	}
+	if page.Prev != nil {
+		prev := page.Prev.Encode()
+		response.Prev = &prev
+	}
+	return response
+}
+
+func newAPISnippets(snippets []models.Snippet) []apiSnippet {
+	list := make([]apiSnippet, 0, len(snippets))
+	for _, s := range snippets {
+		list = append(list, newAPISnippet(s))
+	}
+	return list
+}
+
+// apiSnippetInput is the body of the create and update requests. A language left out is detected. The expiry and
+// visibility left out get defaults that depend on the request: see apiSnippetCreate and apiSnippetUpdate.
+type apiSnippetInput struct {
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Language   *string   `json:"language" openapi:"optional"`
+	Filename   string    `json:"filename" openapi:"optional"`
+	Files      []apiFile `json:"files" openapi:"optional"`
+	Expires    *int      `json:"expires" openapi:"optional" enum:"1,7,365"`
+	Visibility string    `json:"visibility" openapi:"optional" enum:"public,unlisted,private"`
+	Tags       []string  `json:"tags" openapi:"optional"`
+	MaxViews   int       `json:"max_views" openapi:"optional"`
+	Password   string    `json:"password" openapi:"optional"`
+}
+
+// form converts the input into the snippet form, so that the API validates snippets exactly like the HTML pages.
+// The expiry and visibility left out of the input are the given ones.
+func (input apiSnippetInput) form(expires int, visibility string) snippetCreateForm {
+	form := snippetCreateForm{
+		Title:      input.Title,
+		Content:    input.Content,
+		Language:   autoLanguage,
+		Filename:   strings.TrimSpace(input.Filename),
+		Expires:    expires,
+		Visibility: input.Visibility,
+		Tags:       strings.Join(input.Tags, ","),
+		MaxViews:   input.MaxViews,
+		Password:   input.Password,
+	}
+
+	if input.Language != nil {
+		form.Language = *input.Language
+	}
+	if input.Expires != nil {
+		form.Expires = *input.Expires
+	}
+	if form.Visibility == "" {
+		form.Visibility = visibility
+	}
+
+	for _, f := range input.Files {
+		file := snippetFileField{Name: strings.TrimSpace(f.Name), Language: autoLanguage, Content: f.Content}
+		if f.Language != nil {
+			file.Language = *f.Language
+		}
+		form.Files = append(form.Files, file)
+	}
+
+	return form
+}
+
+// apiSnippetID reads the {id} path value, answering with a 404 when it isn't a valid ID.
+func (app *application) apiSnippetID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.apiNotFound(w, r)
+		return 0, false
+	}
+	return id, true
+}
+
+// apiOwnedSnippet loads the {id} snippet for a change, which only its owner may make.
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	id, ok := app.apiSnippetID(w, r)
+	if !ok {
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		app.apiNotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.apiError(w, r, http.StatusForbidden, "only the owner of the snippet can change it")
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// apiSnippetList lists the public snippets, a page at a time. It takes the sort, order, after and before parameters
+// of the /snippets page, and the cursors of the neighbouring pages come back as next and prev.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	q, _, ok := parsePageQuery(r.URL.Query(), apiPageSize)
+	if !ok {
+		app.apiError(w, r, http.StatusBadRequest, "invalid sort, order or cursor parameter")
+		return
+	}
+
+	page, err := app.snippets.Page(q)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, newAPISnippetsPage(page))
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiUserSnippets lists the snippets of the authenticated user, like their dashboard, a page at a time like
+// apiSnippetList.
+func (app *application) apiUserSnippets(w http.ResponseWriter, r *http.Request) {
+	q, _, ok := parsePageQuery(r.URL.Query(), apiPageSize)
+	if !ok {
+		app.apiError(w, r, http.StatusBadRequest, "invalid sort, order or cursor parameter")
+		return
+	}
+
+	page, err := app.snippets.ByUser(app.authenticatedUserID(r), q)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, newAPISnippetsPage(page))
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiSnippetView sends a snippet with the same rules as its page. There is no session to remember an unlock in, so the
+// passphrase of a protected snippet is sent with every request, in the X-Snippet-Passphrase header.
+func (app *application) apiSnippetView(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.apiSnippetID(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	if !snippet.VisibleTo(userID) {
+		app.apiNotFound(w, r)
+		return
+	}
+
+	if snippet.IsProtected() && snippet.UserID != userID {
+		passphrase := r.Header.Get("X-Snippet-Passphrase")
+		if passphrase == "" {
+			app.apiError(w, r, http.StatusForbidden, "this snippet is protected, send its passphrase in the X-Snippet-Passphrase header")
+			return
+		}
+
+		err = app.snippets.Unlock(snippet.ID, passphrase)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrInvalideCredentials):
+				app.apiError(w, r, http.StatusForbidden, "the passphrase is incorrect")
+			case errors.Is(err, models.ErrNoRecord):
+				app.apiNotFound(w, r)
+			default:
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	if snippet.ViewsRemaining.Valid && snippet.UserID != userID {
+		err = app.snippets.ConsumeView(snippet.ID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.apiNotFound(w, r)
+			} else {
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+
+		snippet.ViewsRemaining.Int32--
+	}
+
+	err = app.writeJSON(w, http.StatusOK, apiSnippetResponse{Snippet: newAPISnippet(snippet)})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiSnippetCreate creates a snippet, which expires in 7 days and is public unless the input says otherwise, like with
+// the create form. It answers with the ID and the address of its page, the Location header pointing at the snippet in
+// the API.
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input apiSnippetInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	form := input.form(7, models.VisibilityPublic)
+	form.validate()
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	id, err := app.snippets.Insert(app.authenticatedUserID(r), form.input())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/snippets/%d", id))
+
+	err = app.writeJSON(w, http.StatusCreated, apiSavedResponse{ID: id, URL: fmt.Sprintf("%s/snippet/view/%d", baseURL(r), id)})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiSnippetUpdate replaces a snippet like its edit form does: the view limit and the passphrase are kept as they are,
+// and a request trying to change them is rejected rather than silently ignored. The expiry date and the visibility
+// left out are kept as well, so that a partial update can't make a private snippet public.
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var input apiSnippetInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	form := input.form(models.KeepExpires, snippet.Visibility)
+	form.editing = true
+	form.validate()
+	// Only leaving expires out keeps the expiry date, the value of models.KeepExpires isn't part of the API.
+	form.CheckField(input.Expires == nil || *input.Expires != models.KeepExpires, "expires", "This field must equal to 1, 7 or 365")
+	form.CheckField(input.MaxViews == 0, "maxViews", "The view limit can only be set when the snippet is created")
+	form.CheckField(input.Password == "", "password", "The passphrase can only be set when the snippet is created")
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, apiSavedResponse{ID: snippet.ID, URL: fmt.Sprintf("%s/snippet/view/%d", baseURL(r), snippet.ID)})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+	"snippetbox.hichammou/internal/models"
+	"snippetbox.hichammou/internal/models/mocks"
+)
+
+// decodeJSON decodes a response body, failing the test when it isn't valid JSON.
+func decodeJSON[T any](t *testing.T, body string) T {
+	var v T
+	err := json.Unmarshal([]byte(body), &v)
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %s", body, err)
+	}
+	return v
+}
+
+func TestAPISnippetView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+
+	withPassphrase := func(passphrase string) http.Header {
+		header := http.Header{}
+		header.Set("X-Snippet-Passphrase", passphrase)
+		return header
+	}
+
+	tests := []struct {
+		name      string
+		urlPath   string
+		header    http.Header
+		wantCode  int
+		wantTitle string
+	}{
+		{
+			name:      "Valid ID",
+			urlPath:   "/api/v1/snippets/1",
+			wantCode:  http.StatusOK,
+			wantTitle: "An old silent pond",
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/api/v1/snippets/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/api/v1/snippets/foo",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/api/v1/snippets/4",
+			header:   bearer(validToken),
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "Burn after reading",
+			urlPath:   "/api/v1/snippets/5",
+			wantCode:  http.StatusOK,
+			wantTitle: "Burn after reading",
+		},
+		{
+			name:     "Protected without passphrase",
+			urlPath:  "/api/v1/snippets/6",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Protected with a wrong passphrase",
+			urlPath:  "/api/v1/snippets/6",
+			header:   withPassphrase("abracadabra"),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:      "Protected with the passphrase",
+			urlPath:   "/api/v1/snippets/6",
+			header:    withPassphrase("open sesame"),
+			wantCode:  http.StatusOK,
+			wantTitle: "Behind closed doors",
+		},
+		{
+			name:     "Unknown token",
+			urlPath:  "/api/v1/snippets/1",
+			header:   bearer("NOTATOKEN"),
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.request(t, http.MethodGet, tt.urlPath, tt.header, "")
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Content-Type"), "application/json")
+			spec.check(t, http.MethodGet, tt.urlPath, code, headers, body)
+
+			if tt.wantCode != http.StatusOK {
+				assert.Equal(t, decodeJSON[apiErrorResponse](t, body).Error != "", true)
+				return
+			}
+
+			response := decodeJSON[apiSnippetResponse](t, body)
+			assert.Equal(t, response.Snippet.Title, tt.wantTitle)
+		})
+	}
+
+	t.Run("Files", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/snippets/3", nil, "")
+		spec.check(t, http.MethodGet, "/api/v1/snippets/3", code, headers, body)
+
+		snippet := decodeJSON[apiSnippetResponse](t, body).Snippet
+		assert.Equal(t, snippet.Filename, "main.go")
+		assert.Equal(t, len(snippet.Files), 1)
+		assert.Equal(t, snippet.Files[0].Name, "Dockerfile")
+		assert.Equal(t, *snippet.ForkedFrom, 1)
+	})
+}
+
+func TestAPISnippetList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+
+	t.Run("Public snippets", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/snippets?sort=title", nil, "")
+		assert.Equal(t, code, http.StatusOK)
+		spec.check(t, http.MethodGet, "/api/v1/snippets", code, headers, body)
+
+		response := decodeJSON[apiSnippetsResponse](t, body)
+		assert.Equal(t, len(response.Snippets), 1)
+		assert.Equal(t, response.Next != nil, true)
+		assert.Equal(t, response.Prev == nil, true)
+	})
+
+	t.Run("Invalid sort", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/snippets?sort=views", nil, "")
+		assert.Equal(t, code, http.StatusBadRequest)
+		spec.check(t, http.MethodGet, "/api/v1/snippets", code, headers, body)
+	})
+
+	t.Run("Own snippets", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/user/snippets", bearer(validToken), "")
+		assert.Equal(t, code, http.StatusOK)
+		spec.check(t, http.MethodGet, "/api/v1/user/snippets", code, headers, body)
+
+		response := decodeJSON[apiSnippetsResponse](t, body)
+		assert.Equal(t, len(response.Snippets), 3)
+		assert.Equal(t, response.Next != nil, true)
+	})
+
+	// A listing takes no passphrase, so whatever it lists, the content of protected snippets stays out of it.
+	t.Run("No protected content", func(t *testing.T) {
+		for _, urlPath := range []string{"/api/v1/snippets", "/api/v1/user/snippets"} {
+			code, headers, body := ts.request(t, http.MethodGet, urlPath, bearer(validToken), "")
+			assert.Equal(t, code, http.StatusOK)
+			spec.check(t, http.MethodGet, urlPath, code, headers, body)
+
+			for _, s := range decodeJSON[apiSnippetsResponse](t, body).Snippets {
+				if s.Protected {
+					assert.Equal(t, s.Content, "")
+					assert.Equal(t, len(s.Files), 0)
+				}
+			}
+			assert.Equal(t, strings.Contains(body, "The spare key is under the mat"), false)
+			assert.Equal(t, strings.Contains(body, "The alarm code is 1234"), false)
+		}
+	})
+
+	t.Run("Own snippets with an invalid cursor", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/user/snippets?after=foo", bearer(validToken), "")
+		assert.Equal(t, code, http.StatusBadRequest)
+		spec.check(t, http.MethodGet, "/api/v1/user/snippets", code, headers, body)
+	})
+
+	t.Run("Own snippets with a read token", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/user/snippets", bearer(readToken), "")
+		assert.Equal(t, code, http.StatusOK)
+		spec.check(t, http.MethodGet, "/api/v1/user/snippets", code, headers, body)
+	})
+
+	t.Run("Own snippets without a token", func(t *testing.T) {
+		code, headers, body := ts.request(t, http.MethodGet, "/api/v1/user/snippets", nil, "")
+		assert.Equal(t, code, http.StatusUnauthorized)
+		spec.check(t, http.MethodGet, "/api/v1/user/snippets", code, headers, body)
+		assert.Equal(t, headers.Get("WWW-Authenticate"), "Bearer")
+	})
+}
+
+func TestAPISnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+
+	tests := []struct {
+		name            string
+		token           string
+		body            string
+		wantCode        int
+		wantFieldErrors []string
+	}{
+		{
+			name:     "Valid snippet",
+			token:    validToken,
+			body:     `{"title": "Web service", "filename": "Dockerfile", "content": "FROM golang:1.23", "files": [{"name": "config.yaml", "content": "port: 4000"}], "tags": ["docker"]}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "No token",
+			body:     `{"title": "Web service", "content": "FROM golang:1.23"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:            "Invalid fields",
+			token:           validToken,
+			body:            `{"title": "", "content": "FROM golang:1.23", "expires": 3, "max_views": 5000, "files": [{"name": "", "content": "port: 4000"}]}`,
+			wantCode:        http.StatusUnprocessableEntity,
+			wantFieldErrors: []string{"title", "expires", "max_views", "filename", "files.0"},
+		},
+		{
+			name:     "Badly-formed JSON",
+			token:    validToken,
+			body:     `{"title": "Web service",`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown field",
+			token:    validToken,
+			body:     `{"title": "Web service", "content": "FROM golang:1.23", "expiry": 7}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Two values",
+			token:    validToken,
+			body:     `{"title": "Web service", "content": "FROM golang:1.23"} {}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.request(t, http.MethodPost, "/api/v1/snippets", bearer(tt.token), tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			spec.check(t, http.MethodPost, "/api/v1/snippets", code, headers, body)
+
+			if code == http.StatusCreated {
+				assert.Equal(t, headers.Get("Location"), "/api/v1/snippets/2")
+
+				response := decodeJSON[apiSavedResponse](t, body)
+				assert.Equal(t, response.ID, 2)
+				assert.Equal(t, response.URL, ts.URL+"/snippet/view/2")
+				return
+			}
+
+			response := decodeJSON[apiValidationResponse](t, body)
+			assert.Equal(t, len(response.FieldErrors), len(tt.wantFieldErrors))
+			for _, field := range tt.wantFieldErrors {
+				assert.Equal(t, response.FieldErrors[field] != "", true)
+			}
+		})
+	}
+}
+
+func TestAPISnippetUpdateDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+
+	const snippet = `{"title": "An old silent pond", "content": "A frog jumps into the pond"}`
+
+	tests := []struct {
+		name     string
+		method   string
+		urlPath  string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "Update", method: http.MethodPut, urlPath: "/api/v1/snippets/1", token: validToken, body: snippet, wantCode: http.StatusOK},
+		{name: "Update with invalid fields", method: http.MethodPut, urlPath: "/api/v1/snippets/1", token: validToken, body: `{"title": "", "content": ""}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "Update of the view limit", method: http.MethodPut, urlPath: "/api/v1/snippets/1", token: validToken, body: `{"title": "An old silent pond", "content": "A frog", "max_views": 3}`, wantCode: http.StatusUnprocessableEntity, wantBody: `"max_views": "The view limit can only be set when the snippet is created"`},
+		{name: "Update of the passphrase", method: http.MethodPut, urlPath: "/api/v1/snippets/1", token: validToken, body: `{"title": "An old silent pond", "content": "A frog", "password": "open sesame"}`, wantCode: http.StatusUnprocessableEntity, wantBody: `"password": "The passphrase can only be set when the snippet is created"`},
+		{name: "Update of another user's snippet", method: http.MethodPut, urlPath: "/api/v1/snippets/3", token: validToken, body: snippet, wantCode: http.StatusForbidden},
+		{name: "Update of a private snippet", method: http.MethodPut, urlPath: "/api/v1/snippets/4", token: validToken, body: snippet, wantCode: http.StatusNotFound},
+		{name: "Update without a token", method: http.MethodPut, urlPath: "/api/v1/snippets/1", body: snippet, wantCode: http.StatusUnauthorized},
+		{name: "Delete", method: http.MethodDelete, urlPath: "/api/v1/snippets/1", token: validToken, wantCode: http.StatusNoContent},
+		{name: "Delete of another user's snippet", method: http.MethodDelete, urlPath: "/api/v1/snippets/3", token: validToken, wantCode: http.StatusForbidden},
+		{name: "Delete of a non-existent snippet", method: http.MethodDelete, urlPath: "/api/v1/snippets/2", token: validToken, wantCode: http.StatusNotFound},
+		{name: "Update with a read token", method: http.MethodPut, urlPath: "/api/v1/snippets/1", token: readToken, body: snippet, wantCode: http.StatusForbidden},
+		{name: "Delete with a read token", method: http.MethodDelete, urlPath: "/api/v1/snippets/1", token: readToken, wantCode: http.StatusForbidden},
+		{name: "Delete without a token", method: http.MethodDelete, urlPath: "/api/v1/snippets/1", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.request(t, tt.method, tt.urlPath, bearer(tt.token), tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			spec.check(t, tt.method, tt.urlPath, code, headers, body)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			if code >= 400 {
+				assert.Equal(t, strings.HasPrefix(body, "{"), true)
+			}
+		})
+	}
+}
+
+func TestAPISnippetPartialUpdate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+	snippets := app.snippets.(*mocks.SnippetModel)
+
+	tests := []struct {
+		name           string
+		body           string
+		wantCode       int
+		wantVisibility string
+		wantExpires    int
+	}{
+		{
+			name:           "Without visibility and expires",
+			body:           `{"title": "A hidden draft", "content": "Almost ready"}`,
+			wantCode:       http.StatusOK,
+			wantVisibility: models.VisibilityPrivate,
+			wantExpires:    models.KeepExpires,
+		},
+		{
+			name:           "With visibility and expires",
+			body:           `{"title": "A hidden draft", "content": "Ready", "visibility": "public", "expires": 365}`,
+			wantCode:       http.StatusOK,
+			wantVisibility: models.VisibilityPublic,
+			wantExpires:    365,
+		},
+		{
+			name:     "With the value that keeps the expiry",
+			body:     `{"title": "A hidden draft", "content": "Ready", "expires": 0}`,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.request(t, http.MethodPut, "/api/v1/snippets/9", bearer(validToken), tt.body)
+			assert.Equal(t, code, tt.wantCode)
+			spec.check(t, http.MethodPut, "/api/v1/snippets/9", code, headers, body)
+
+			if code == http.StatusOK {
+				input, ok := snippets.LastUpdate()
+				assert.Equal(t, ok, true)
+				assert.Equal(t, input.Visibility, tt.wantVisibility)
+				assert.Equal(t, input.Expires, tt.wantExpires)
+			}
+		})
+	}
+}
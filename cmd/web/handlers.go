@@ -1,26 +1,227 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"snippetbox.hichammou/internal/diff"
+	"snippetbox.hichammou/internal/highlight"
+	"snippetbox.hichammou/internal/langdetect"
 	"snippetbox.hichammou/internal/models"
 	"snippetbox.hichammou/internal/validator"
 )
 
+// autoLanguage is the language form value asking for the language to be detected from the content.
+const autoLanguage = "auto"
+
+// Size limits of the files of a snippet. A single file has to fit in a TEXT column.
+const (
+	maxFiles       = 10
+	maxFileSize    = 65535
+	maxSnippetSize = 256 * 1024
+)
+
+// snippetFileField holds the fields of one of the extra files of the snippet form.
+type snippetFileField struct {
+	Name     string
+	Language string
+	Content  string
+}
+
 // Define a snippetCreateForm struct to represent the form data and validation errors for the form fields.
 type snippetCreateForm struct {
-	Title   string
-	Content string
-	Expires int
+	Title    string
+	Content  string
+	Language string
+	// Filename is the name of the first file. Files are the files that come after it, their errors are reported
+	// under the "file_<index>" keys.
+	Filename   string
+	Files      []snippetFileField
+	Expires    int
+	Visibility string
+	// AddFile is set when the form was posted with the "Add a file" button, which browsers without JavaScript use.
+	AddFile bool
+	// Tags is the raw comma separated list typed by the user.
+	Tags string
+	// MaxViews is the number of reads before the snippet self-destructs, 0 meaning no limit.
+	MaxViews int
+	// Password is an optional passphrase that readers have to type before they can see the content.
+	Password string
+	// editing is set by the edit handlers, whose form can keep the current expiry date with models.KeepExpires.
+	editing bool
 	// Here we Embedded the Validator stuct, mean that our snippetCreateForm inherits all the fields and methods of the Validator stuct
 	validator.Validator
 }
 
+// validate checks the snippet fields. It is shared by the create and edit handlers.
+func (form *snippetCreateForm) validate() {
+	form.CheckField(validator.NoBlank(form.Title), "title", "This field can't be empty")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field can't be more than 100 characters long")
+	form.CheckField(validator.NoBlank(form.Content), "content", "This field can't be empty")
+	form.CheckField(validator.MaxBytes(form.Content, maxFileSize), "content", "A file can't be larger than 64 KB")
+	form.validateFiles()
+	tags := parseTags(form.Tags)
+	form.CheckField(validator.MaxItems(tags, 5), "tags", "A snippet can't have more than 5 tags")
+	form.CheckField(validator.AllMaxChars(tags, 30), "tags", "Tags can't be more than 30 characters long")
+	form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, digits and the + # . _ - characters")
+	form.CheckField(form.Language == "" || form.Language == autoLanguage || highlight.Supported(form.Language), "language", "This language isn't supported")
+	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365) || (form.editing && form.Expires == models.KeepExpires), "expires", "This field must equal to 1, 7 or 365")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted or private")
+	form.CheckField(validator.Between(form.MaxViews, 0, 1000), "maxViews", "This field must be between 0 and 1000")
+	// bcrypt ignores everything after the 72nd byte, so longer passphrases would give a false sense of security.
+	form.CheckField(validator.MaxBytes(form.Password, 72), "password", "This field can't be more than 72 bytes long")
+}
+
+// validateFiles checks the names and sizes of the files. Every file needs a distinct name as soon as there is more than one.
+func (form *snippetCreateForm) validateFiles() {
+	form.CheckField(validator.MaxChars(form.Filename, 100), "filename", "This field can't be more than 100 characters long")
+	form.CheckField(!strings.ContainsAny(form.Filename, `/\`), "filename", "A file name can't contain slashes")
+
+	if len(form.Files) == 0 {
+		return
+	}
+
+	form.CheckField(validator.NoBlank(form.Filename), "filename", "Files need a name when a snippet has more than one")
+	form.CheckField(validator.MaxItems(form.Files, maxFiles-1), "files", fmt.Sprintf("A snippet can't have more than %d files", maxFiles))
+
+	names := map[string]bool{form.Filename: true}
+	size := len(form.Content)
+
+	for i, f := range form.Files {
+		key := fmt.Sprintf("file_%d", i)
+		form.CheckField(validator.NoBlank(f.Name), key, "This file needs a name")
+		form.CheckField(validator.MaxChars(f.Name, 100), key, "A file name can't be more than 100 characters long")
+		form.CheckField(!strings.ContainsAny(f.Name, `/\`), key, "A file name can't contain slashes")
+		form.CheckField(!names[f.Name], key, "Another file already has this name")
+		form.CheckField(validator.NoBlank(f.Content), key, "This file can't be empty")
+		form.CheckField(validator.MaxBytes(f.Content, maxFileSize), key, "A file can't be larger than 64 KB")
+		form.CheckField(f.Language == "" || f.Language == autoLanguage || highlight.Supported(f.Language), key, "This language isn't supported")
+
+		names[f.Name] = true
+		size += len(f.Content)
+	}
+
+	form.CheckField(size <= maxSnippetSize, "files", "The files of a snippet can't add up to more than 256 KB")
+}
+
+// input converts the form into the values expected by the snippet model. When the language was left to
+// autodetection the guess is made here, so it gets stored with the snippet and can be changed later on.
+func (form *snippetCreateForm) input() models.SnippetInput {
+	language := form.Language
+	if language == autoLanguage {
+		language = langdetect.Detect(form.Content)
+	}
+
+	var files []models.SnippetFile
+	for _, f := range form.Files {
+		file := models.SnippetFile{Name: f.Name, Language: f.Language, Content: f.Content}
+		if file.Language == autoLanguage {
+			file.Language = langdetect.Detect(f.Content)
+		}
+		files = append(files, file)
+	}
+
+	return models.SnippetInput{
+		Title:      form.Title,
+		Content:    form.Content,
+		Language:   language,
+		Filename:   form.Filename,
+		Files:      files,
+		Expires:    form.Expires,
+		Visibility: form.Visibility,
+		Tags:       parseTags(form.Tags),
+		MaxViews:   form.MaxViews,
+		Password:   form.Password,
+	}
+}
+
+// NewFile returns the fields of a file added to the form, which starts with language detection like the first file.
+func (form snippetCreateForm) NewFile() snippetFileField {
+	return snippetFileField{Language: autoLanguage}
+}
+
+// fileFields converts the extra files of a snippet into form fields, to pre-fill the edit and fork forms.
+func fileFields(files []models.SnippetFile) []snippetFileField {
+	var fields []snippetFileField
+	for _, f := range files {
+		fields = append(fields, snippetFileField{Name: f.Name, Language: f.Language, Content: f.Content})
+	}
+	return fields
+}
+
+type snippetUnlockForm struct {
+	Password string
+	validator.Validator
+}
+
+// parseSnippetForm reads the snippet fields from the request body. It only fails when the form can't be decoded at all,
+// the field values are checked by validate().
+func parseSnippetForm(r *http.Request) (snippetCreateForm, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return snippetCreateForm{}, err
+	}
+
+	expires, err := strconv.Atoi(r.PostForm.Get("expires"))
+	if err != nil {
+		return snippetCreateForm{}, err
+	}
+
+	// The view limit is optional, an empty field means the snippet can be read any number of times.
+	maxViews := 0
+	if v := r.PostForm.Get("maxViews"); v != "" {
+		maxViews, err = strconv.Atoi(v)
+		if err != nil {
+			return snippetCreateForm{}, err
+		}
+	}
+
+	// The extra files come as repeated fields, one value of each per file. A file left completely empty is dropped,
+	// which is how files get removed without JavaScript.
+	names, languages, contents := r.PostForm["file_name"], r.PostForm["file_language"], r.PostForm["file_content"]
+	if len(names) != len(languages) || len(names) != len(contents) {
+		return snippetCreateForm{}, errors.New("mismatched file fields")
+	}
+
+	var files []snippetFileField
+	for i := range names {
+		f := snippetFileField{Name: strings.TrimSpace(names[i]), Language: languages[i], Content: contents[i]}
+		if f.Name == "" && strings.TrimSpace(f.Content) == "" {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	// doing this manualy is fine because our form has only a few fields. But if the form is very large consider using a form decoder package
+	// like go-playground/form to save you typing.
+	form := snippetCreateForm{
+		Title:      r.PostForm.Get("title"),
+		Content:    r.PostForm.Get("content"),
+		Language:   r.PostForm.Get("language"),
+		Filename:   strings.TrimSpace(r.PostForm.Get("filename")),
+		Files:      files,
+		Expires:    expires,
+		Visibility: r.PostForm.Get("visibility"),
+		AddFile:    r.PostForm.Has("add_file"),
+		Tags:       r.PostForm.Get("tags"),
+		MaxViews:   maxViews,
+		Password:   r.PostForm.Get("password"),
+	}
+
+	return form, nil
+}
+
 type UserSignupForm struct {
 	Name     string
+	Handle   string
 	Email    string
 	Password string
 	validator.Validator
@@ -62,11 +263,15 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 
 	form := UserSignupForm{
 		Name:     r.PostForm.Get("name"),
+		Handle:   strings.ToLower(strings.TrimSpace(r.PostForm.Get("handle"))),
 		Email:    r.PostForm.Get("email"),
 		Password: r.PostForm.Get("password"),
 	}
 
 	form.CheckField(validator.NoBlank(form.Name), "name", "This field could not be empty")
+	form.CheckField(validator.MinChars(form.Handle, 3), "handle", "This field must be at least 3 characters long")
+	form.CheckField(validator.MaxChars(form.Handle, 30), "handle", "This field can't be more than 30 characters long")
+	form.CheckField(validator.Match(form.Handle, validator.HandleRX), "handle", "This field must start with a letter and only contain letters, digits, - and _")
 	form.CheckField(validator.NoBlank(form.Email), "email", "This field could not be empty")
 	form.CheckField(validator.Match(form.Email, validator.EmailRX), "email", "This field must be a valide email address")
 	form.CheckField(validator.NoBlank(form.Password), "password", "This field could not be empty")
@@ -81,10 +286,14 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	err = app.users.Insert(form.Name, form.Handle, form.Email, form.Password)
 	if err != nil {
-		if errors.Is(err, models.ErrDuplicateEmail) {
-			form.AddFieldError("email", "Email address is already in use")
+		if errors.Is(err, models.ErrDuplicateEmail) || errors.Is(err, models.ErrDuplicateHandle) {
+			if errors.Is(err, models.ErrDuplicateEmail) {
+				form.AddFieldError("email", "Email address is already in use")
+			} else {
+				form.AddFieldError("handle", "This handle is already taken")
+			}
 
 			data := app.newTemplateData(r)
 			data.Form = form
@@ -267,31 +476,114 @@ func (app *application) Account(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, http.StatusOK, "account.html", data)
 }
 
-// Snippet pages
+type tokenForm struct {
+	Name  string
+	Scope string
+	// Expires is the lifetime of the token in days, 0 meaning it never expires.
+	Expires int
+	validator.Validator
+}
 
-func (app *application) Home(w http.ResponseWriter, r *http.Request) {
-	snippets, err := app.snippets.Latest()
+// renderTokens shows the tokens page with the given form, and the token just created when there is one.
+func (app *application) renderTokens(w http.ResponseWriter, r *http.Request, status int, form tokenForm, token string) {
+	tokens, err := app.tokens.ForUser(app.authenticatedUserID(r))
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
 	data := app.newTemplateData(r)
+	data.Tokens = tokens
+	data.Token = token
+	data.BaseURL = baseURL(r)
+	data.Form = form
 
-	data.Snippets = snippets
+	app.render(w, r, status, "tokens.html", data)
+}
 
-	app.render(w, r, http.StatusOK, "home.html", data)
+func (app *application) userTokens(w http.ResponseWriter, r *http.Request) {
+	app.renderTokens(w, r, http.StatusOK, tokenForm{Scope: models.ScopeWrite, Expires: 90}, "")
 }
 
-func (app *application) SnippetView(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(r.PathValue("id"))
+// userTokensPost creates an API token for the current user. The token is only shown in the response to this request,
+// the database keeps nothing but its hash.
+func (app *application) userTokensPost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
 
-	if err != nil || id < 1 {
+	expires, err := strconv.Atoi(r.PostForm.Get("expires"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := tokenForm{
+		Name:    strings.TrimSpace(r.PostForm.Get("name")),
+		Scope:   r.PostForm.Get("scope"),
+		Expires: expires,
+	}
+
+	form.CheckField(validator.NoBlank(form.Name), "name", "This field can't be empty")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field can't be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(form.Scope, models.ScopeRead, models.ScopeWrite), "scope", "This field must be read or write")
+	form.CheckField(validator.PermittedValue(form.Expires, 0, 30, 90, 365), "expires", "This field must equal to 0, 30, 90 or 365")
+
+	if !form.Valid() {
+		app.renderTokens(w, r, http.StatusUnprocessableEntity, form, "")
+		return
+	}
+
+	token, err := app.tokens.Insert(app.authenticatedUserID(r), models.TokenInput{Name: form.Name, Scope: form.Scope, Expires: form.Expires})
+	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	snippet, err := app.snippets.Get(id)
+	app.renderTokens(w, r, http.StatusOK, tokenForm{Scope: models.ScopeWrite, Expires: 90}, token)
+}
+
+func (app *application) userTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The model only revokes the tokens of the given user, someone else's token is as good as a missing one.
+	err = app.tokens.Revoke(app.authenticatedUserID(r), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "The token has been revoked.")
+	http.Redirect(w, r, "/user/account/tokens", http.StatusSeeOther)
+}
+
+// userProfile shows the public side of a user: their bio, join date and public snippets, a page at a time like
+// SnippetList. The {user} path value is either a user ID or a handle, handles never starting with a digit.
+func (app *application) userProfile(w http.ResponseWriter, r *http.Request) {
+	q, order, ok := parsePageQuery(r.URL.Query(), pageSize)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	var err error
+
+	if id, convErr := strconv.Atoi(r.PathValue("user")); convErr == nil {
+		user, err = app.users.Get(id)
+	} else {
+		user, err = app.users.GetByHandle(strings.ToLower(r.PathValue("user")))
+	}
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			http.NotFound(w, r)
@@ -301,66 +593,1231 @@ func (app *application) SnippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page, err := app.snippets.PublicByUser(user.ID, q)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
-	data.Snippet = snippet
+	data.User = user
+	data.Page = page
+	data.Sort = q.Sort
+	data.Order = order
 
-	app.render(w, r, http.StatusOK, "view.html", data)
+	app.render(w, r, http.StatusOK, "profile.html", data)
 }
 
-func (app *application) SnippetCreate(w http.ResponseWriter, r *http.Request) {
-	data := app.newTemplateData(r)
+type userProfileForm struct {
+	Bio string
+	validator.Validator
+}
 
-	// Initialize a new createSnippetForm instance and pass it to the template.
-	data.Form = snippetCreateForm{
-		Expires: 7,
+func (app *application) userEditProfile(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
 	}
-	app.render(w, r, http.StatusOK, "create.html", data)
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Form = userProfileForm{Bio: user.Bio}
+
+	app.render(w, r, http.StatusOK, "edit-profile.html", data)
 }
 
-func (app *application) SnippetCreatePost(w http.ResponseWriter, r *http.Request) {
+func (app *application) userEditProfilePost(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	expires, err := strconv.Atoi(r.PostForm.Get("expires"))
+	form := userProfileForm{
+		Bio: strings.TrimSpace(r.PostForm.Get("bio")),
+	}
+
+	form.CheckField(validator.MaxChars(form.Bio, 500), "bio", "This field can't be more than 500 characters long")
+
+	userID := app.authenticatedUserID(r)
+
+	if !form.Valid() {
+		user, err := app.users.Get(userID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data := app.newTemplateData(r)
+		data.User = user
+		data.Form = form
+
+		app.render(w, r, http.StatusUnprocessableEntity, "edit-profile.html", data)
+		return
+	}
+
+	err = app.users.UpdateBio(userID, form.Bio)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your profile has been updated.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+func (app *application) userStars(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Starred(app.authenticatedUserID(r))
 	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "stars.html", data)
+}
+
+// The bulk actions offered on the snippets dashboard.
+const (
+	bulkDelete     = "delete"
+	bulkExtend     = "extend"
+	bulkVisibility = "visibility"
+)
+
+type userSnippetsForm struct {
+	IDs        []int
+	Action     string
+	Expires    int
+	Visibility string
+	validator.Validator
+}
+
+// Selected reports whether the snippet was checked when the form was submitted.
+func (form userSnippetsForm) Selected(id int) bool {
+	return slices.Contains(form.IDs, id)
+}
+
+// userSnippets is the dashboard of the user, listing their snippets a page at a time like SnippetList.
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	q, order, ok := parsePageQuery(r.URL.Query(), pageSize)
+	if !ok {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	// doing this manualy is fine because our form has only three fields. But if the form is very large consider using a form decoder package
-	// like go-playground/form to save you typing.
+	page, err := app.snippets.ByUser(app.authenticatedUserID(r), q)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	form := snippetCreateForm{
-		Title:   r.PostForm.Get("title"),
-		Content: r.PostForm.Get("content"),
-		Expires: expires,
+	data := app.newTemplateData(r)
+	data.Page = page
+	data.Sort = q.Sort
+	data.Order = order
+	data.Form = userSnippetsForm{Expires: 7, Visibility: models.VisibilityPublic}
+
+	app.render(w, r, http.StatusOK, "dashboard.html", data)
+}
+
+// userSnippetsPost applies one bulk action to the snippets checked on the dashboard.
+func (app *application) userSnippetsPost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 
-	form.CheckField(validator.NoBlank(form.Title), "title", "This field can't be empty")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field can't be more than 100 characters long")
-	form.CheckField(validator.NoBlank(form.Content), "content", "This field can't be empty")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 356), "expires", "This field must equal to 1, 7 aor 365")
+	form := userSnippetsForm{
+		Action:     r.PostForm.Get("action"),
+		Visibility: r.PostForm.Get("visibility"),
+	}
+
+	for _, value := range r.PostForm["id"] {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		form.IDs = append(form.IDs, id)
+	}
+
+	if v := r.PostForm.Get("expires"); v != "" {
+		form.Expires, err = strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(form.IDs) == 0 {
+		form.AddNonFieldError("Select at least one snippet")
+	}
+	form.CheckField(validator.PermittedValue(form.Action, bulkDelete, bulkExtend, bulkVisibility), "action", "This action is not supported")
+	if form.Action == bulkExtend {
+		form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	}
+	if form.Action == bulkVisibility {
+		form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted or private")
+	}
+
+	userID := app.authenticatedUserID(r)
 
 	if !form.Valid() {
+		q, order, ok := parsePageQuery(r.URL.Query(), pageSize)
+		if !ok {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		page, err := app.snippets.ByUser(userID, q)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
 		data := app.newTemplateData(r)
+		data.Page = page
+		data.Sort = q.Sort
+		data.Order = order
 		data.Form = form
-		app.render(w, r, http.StatusUnprocessableEntity, "create.html", data)
+
+		app.render(w, r, http.StatusUnprocessableEntity, "dashboard.html", data)
+		return
+	}
+
+	var n int
+	var done string
+
+	switch form.Action {
+	case bulkDelete:
+		n, err = app.snippets.DeleteMany(userID, form.IDs)
+		done = "deleted"
+	case bulkExtend:
+		n, err = app.snippets.ExtendMany(userID, form.IDs, form.Expires)
+		done = "extended"
+	case bulkVisibility:
+		n, err = app.snippets.SetVisibilityMany(userID, form.IDs, form.Visibility)
+		done = "made " + form.Visibility
+	}
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	noun := "snippets"
+	if n == 1 {
+		noun = "snippet"
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%d %s %s.", n, noun, done))
+
+	http.Redirect(w, r, "/user/snippets", http.StatusSeeOther)
+}
+
+// Snippet pages
 
+func (app *application) Home(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Latest()
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	data := app.newTemplateData(r)
 
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "home.html", data)
+}
+
+// pageSize is the number of snippets on each page of the /snippets listing.
+const pageSize = 20
+
+// SnippetList browses every live public snippet, a page at a time. The sort and order query parameters choose the
+// order, and the after and before parameters hold the cursor of the page next to the one wanted.
+func (app *application) SnippetList(w http.ResponseWriter, r *http.Request) {
+	q, order, ok := parsePageQuery(r.URL.Query(), pageSize)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	page, err := app.snippets.Page(q)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Page = page
+	data.Sort = q.Sort
+	data.Order = order
+
+	app.render(w, r, http.StatusOK, "list.html", data)
+}
+
+// TagView lists the public snippets carrying the {tag} path value, a page at a time like SnippetList.
+func (app *application) TagView(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(r.PathValue("tag"))
+
+	q, order, ok := parsePageQuery(r.URL.Query(), pageSize)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	page, err := app.snippets.ByTag(tag, q)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Page = page
+	data.Sort = q.Sort
+	data.Order = order
+
+	app.render(w, r, http.StatusOK, "tag.html", data)
+}
+
+// searchLimit caps the number of results shown for a search.
+const searchLimit = 50
+
+func (app *application) SearchView(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+
+	q := models.ParseSearchQuery(data.Query)
+	if !q.IsEmpty() {
+		snippets, err := app.snippets.Search(q, searchLimit)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		for _, snippet := range snippets {
+			data.SearchResults = append(data.SearchResults, searchResult{
+				Snippet: snippet,
+				Excerpt: excerpt(snippet.Content, q.Words(), 200),
+			})
+		}
+	}
+
+	app.render(w, r, http.StatusOK, "search.html", data)
+}
+
+func (app *application) SnippetView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil || id < 1 {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// ?lines=5-12 shows only that slice of the snippet. It is checked first so that a mistyped range doesn't use up a view.
+	var lineStart, lineEnd int
+	if v := r.URL.Query().Get("lines"); v != "" {
+		var ok bool
+		lineStart, lineEnd, ok = parseLineRange(v)
+		if !ok {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Private snippets answer with a 404 rather than a 403, so that their existence isn't revealed either.
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Password-protected snippets show the unlock form instead of the content until the passphrase was typed.
+	// This happens before the view limit is checked, so looking at the form doesn't use up a view.
+	if !app.isUnlocked(r, snippet) {
+		snippet.Content = ""
+		snippet.Files = nil
+
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Locked = true
+		data.Form = snippetUnlockForm{}
+
+		app.render(w, r, http.StatusOK, "view.html", data)
+		return
+	}
+
+	// The range is also checked against the content before consumeView, so that it doesn't use up a view either.
+	if lineStart > highlight.LineCount(snippet.Content) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if !app.consumeView(w, r, &snippet) {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	if data.IsAuthenticated {
+		data.Starred, err = app.snippets.IsStarred(data.UserID, snippet.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	comments, err := app.comments.ForSnippet(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Lines, data.Comments = annotate(snippet.Language, snippet.Content, comments)
+
+	if lineStart > 0 {
+		data.LineCount = len(data.Lines)
+		data.Lines = data.Lines[lineStart-1 : min(lineEnd, len(data.Lines))]
+	}
+
+	app.render(w, r, http.StatusOK, "view.html", data)
+}
+
+// consumeView uses up one view of a view-limited snippet, except for its owner who would otherwise burn it right after
+// creating it. When another reader took the last view in the meantime the snippet is gone: it answers with a 404
+// and returns false.
+func (app *application) consumeView(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.ViewsRemaining.Valid || snippet.UserID == app.authenticatedUserID(r) {
+		return true
+	}
+
+	err := app.snippets.ConsumeView(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return false
+	}
+
+	snippet.ViewsRemaining.Int32--
+
+	return true
+}
+
+// SnippetRaw sends a file of the snippet as plain text, which is what scripts fetch with curl. It is the first file
+// unless the file query parameter names another one.
+func (app *application) SnippetRaw(w http.ResponseWriter, r *http.Request) {
+	app.sendSnippetFile(w, r, false)
+}
+
+// SnippetDownload sends the same file as SnippetRaw, as an attachment the browser saves under a sensible name.
+func (app *application) SnippetDownload(w http.ResponseWriter, r *http.Request) {
+	app.sendSnippetFile(w, r, true)
+}
+
+func (app *application) sendSnippetFile(w http.ResponseWriter, r *http.Request, attachment bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Unlike the view page there is no form to type the passphrase in, it has to be unlocked from the view page first.
+	if !app.isUnlocked(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	// The file is looked up before the view is used up, so that a wrong name doesn't burn it.
+	file, ok := snippet.File(r.URL.Query().Get("file"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !app.consumeView(w, r, &snippet) {
+		return
+	}
+
+	// X-Content-Type-Options: nosniff, set by commonHeaders, stops browsers from rendering the text as HTML.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if attachment {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName(snippet.Title, file)}))
+	}
+
+	w.Write([]byte(file.Content))
+}
+
+// SnippetStarPost stars the snippet for the current user, or unstars it when it already was.
+func (app *application) SnippetStarPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := app.snippets.ToggleStar(app.authenticatedUserID(r), snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// SnippetUnlockPost checks the passphrase of a protected snippet. On success the unlock is remembered in the session
+// for that snippet only, and the visitor is sent back to it.
+func (app *application) SnippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := snippetUnlockForm{
+		Password: r.PostForm.Get("password"),
+	}
+
+	form.CheckField(validator.NoBlank(form.Password), "password", "This field can't be empty")
+
+	if form.Valid() {
+		err = app.snippets.Unlock(snippet.ID, form.Password)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalideCredentials) {
+				form.AddFieldError("password", "The passphrase is incorrect")
+			} else if errors.Is(err, models.ErrNoRecord) {
+				http.NotFound(w, r)
+				return
+			} else {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+	}
+
+	if !form.Valid() {
+		snippet.Content = ""
+		snippet.Files = nil
+
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Locked = true
+		data.Form = form
+
+		app.render(w, r, http.StatusUnprocessableEntity, "view.html", data)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), unlockedSessionKey(snippet.ID), true)
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+func (app *application) SnippetCreate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+
+	// Initialize a new createSnippetForm instance and pass it to the template.
+	data.Form = snippetCreateForm{
+		Language:   autoLanguage,
+		Expires:    7,
+		Visibility: models.VisibilityPublic,
+	}
+	app.render(w, r, http.StatusOK, "create.html", data)
+}
+
+func (app *application) SnippetCreatePost(w http.ResponseWriter, r *http.Request) {
+	form, err := parseSnippetForm(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.AddFile {
+		form.Files = append(form.Files, form.NewFile())
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusOK, "create.html", data)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "create.html", data)
+		return
+	}
+
+	// requireAuthentification guarantees that a user is logged in, so the snippet is always tied to its author.
+	userID := app.authenticatedUserID(r)
+
+	id, err := app.snippets.Insert(userID, form.input())
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+}
+
+// defaultPasteTitle is the title of the pastes that weren't given one in the query string.
+const defaultPasteTitle = "Untitled paste"
+
+// PastePost creates a snippet from the raw request body, which is what `curl --data-binary @- https://host/p` sends.
+// The other fields come from the query string: title, language (detected when left out), expires (7 days by default)
+// and visibility (unlisted by default, pastes being shared by link). It answers with the URL of the snippet in plain
+// text, or with the validation errors, one per line.
+func (app *application) PastePost(w http.ResponseWriter, r *http.Request) {
+	// One byte more than a file can hold is enough for validate() to tell that the body is too large.
+	content, err := io.ReadAll(io.LimitReader(r.Body, maxFileSize+1))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+
+	form := snippetCreateForm{
+		Title:      cmp.Or(query.Get("title"), defaultPasteTitle),
+		Content:    string(content),
+		Language:   query.Get("language"),
+		Expires:    7,
+		Visibility: cmp.Or(query.Get("visibility"), models.VisibilityUnlisted),
+	}
+
+	// An empty language asks for plain text, like in the form, so only a missing one is detected.
+	if !query.Has("language") {
+		form.Language = autoLanguage
+	}
+
+	if query.Has("expires") {
+		form.Expires, err = strconv.Atoi(query.Get("expires"))
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	form.validate()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !form.Valid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		for _, field := range slices.Sorted(maps.Keys(form.FieldErrors)) {
+			fmt.Fprintf(w, "%s: %s\n", field, form.FieldErrors[field])
+		}
+		return
+	}
+
+	id, err := app.snippets.Insert(app.authenticatedUserID(r), form.input())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/snippet/view/%d", baseURL(r), id)
+
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintln(w, url)
+}
+
+// SnippetFork shows the create form pre-filled with a copy of the snippet. The copy belongs to the current user and keeps
+// a reference to the original once it is saved.
+func (app *application) SnippetFork(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.ForkOf = snippet
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   snippet.Language,
+		Filename:   snippet.Filename,
+		Files:      fileFields(snippet.Files),
+		Expires:    7,
+		Visibility: snippet.Visibility,
+		Tags:       strings.Join(snippet.Tags, ", "),
+	}
+
+	app.render(w, r, http.StatusOK, "create.html", data)
+}
+
+func (app *application) SnippetForkPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	form, err := parseSnippetForm(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.AddFile {
+		form.Files = append(form.Files, form.NewFile())
+
+		data := app.newTemplateData(r)
+		data.ForkOf = snippet
+		data.Form = form
+		app.render(w, r, http.StatusOK, "create.html", data)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.ForkOf = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "create.html", data)
+		return
+	}
+
+	input := form.input()
+	input.ForkedFrom = snippet.ID
+
+	id, err := app.snippets.Insert(app.authenticatedUserID(r), input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully forked!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+}
+
+type commentForm struct {
+	Content string
+	// ParentID is the comment answered by a reply, 0 for a new thread.
+	ParentID int
+	// Lines is the range of lines a new thread is attached to, like "10-14" or "7". It is empty for general comments.
+	Lines     string
+	LineStart int
+	LineEnd   int
+	validator.Validator
+}
+
+func (form *commentForm) validate() {
+	form.CheckField(validator.NoBlank(form.Content), "content", "This field can't be empty")
+	form.CheckField(validator.MaxChars(form.Content, 1000), "content", "This field can't be more than 1000 characters long")
+}
+
+// SnippetCommentPost adds a comment, or a reply when the parent field holds the ID of another comment on the snippet.
+func (app *application) SnippetCommentPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := commentForm{
+		Content: strings.TrimSpace(r.PostForm.Get("content")),
+		Lines:   strings.TrimSpace(r.PostForm.Get("lines")),
+	}
+
+	if v := r.PostForm.Get("parent"); v != "" {
+		form.ParentID, err = strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		parent, err := app.comments.Get(form.ParentID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.clientError(w, http.StatusBadRequest)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		if parent.SnippetID != snippet.ID {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		// Threads are one level deep, answering a reply adds to the thread it belongs to.
+		if parent.ParentID.Valid {
+			form.ParentID = int(parent.ParentID.Int32)
+		}
+
+		// Replies belong to the lines of their thread.
+		form.Lines = ""
+	}
+
+	form.validate()
+
+	if form.Lines != "" {
+		count := len(diff.SplitLines(snippet.Content))
+
+		var ok bool
+		form.LineStart, form.LineEnd, ok = parseLineRange(form.Lines)
+		form.CheckField(ok && form.LineEnd <= count, "lines", fmt.Sprintf("This field must be a line or a range of lines between 1 and %d, like 3 or 3-7", count))
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "comment.html", data)
+		return
+	}
+
+	id, err := app.comments.Insert(snippet.ID, app.authenticatedUserID(r), models.CommentInput{
+		Content:   form.Content,
+		ParentID:  form.ParentID,
+		LineStart: form.LineStart,
+		LineEnd:   form.LineEnd,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d#comment-%d", snippet.ID, id), http.StatusSeeOther)
+}
+
+// ownedComment fetches the comment from the {id} path value for the pages that change it, along with its snippet.
+// Like ownedSnippet it writes a 404 or 403 response itself and returns false when the handler should stop.
+func (app *application) ownedComment(w http.ResponseWriter, r *http.Request) (models.Comment, models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	comment, err := app.comments.Get(id)
+	if err == nil {
+		// The comments of an expired snippet are on their way out, they can't be changed anymore.
+		var snippet models.Snippet
+		snippet, err = app.snippets.Get(comment.SnippetID)
+		if err == nil {
+			if comment.UserID != app.authenticatedUserID(r) {
+				app.clientError(w, http.StatusForbidden)
+				return models.Comment{}, models.Snippet{}, false
+			}
+			return comment, snippet, true
+		}
+	}
+
+	if errors.Is(err, models.ErrNoRecord) {
+		http.NotFound(w, r)
+	} else {
+		app.serverError(w, r, err)
+	}
+	return models.Comment{}, models.Snippet{}, false
+}
+
+func (app *application) CommentEdit(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.ownedComment(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Comment = comment
+	data.Form = commentForm{Content: comment.Content}
+
+	app.render(w, r, http.StatusOK, "comment.html", data)
+}
+
+func (app *application) CommentEditPost(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.ownedComment(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := commentForm{
+		Content: strings.TrimSpace(r.PostForm.Get("content")),
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Comment = comment
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "comment.html", data)
+		return
+	}
+
+	err = app.comments.Update(comment.ID, form.Content)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d#comment-%d", snippet.ID, comment.ID), http.StatusSeeOther)
+}
+
+func (app *application) CommentDeletePost(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.ownedComment(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.comments.Delete(comment.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully deleted!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d#comments", snippet.ID), http.StatusSeeOther)
+}
+
+// viewableSnippet fetches the snippet from the {id} path value for read-only pages.
+// It writes a 404 response itself and returns false when the handler should stop.
+func (app *application) viewableSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	if !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+		return models.Snippet{}, false
+	}
+
+	// The pages using this show the content without going through SnippetView, so they would bypass the view limit.
+	if snippet.ViewsRemaining.Valid && snippet.UserID != app.authenticatedUserID(r) {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+func (app *application) SnippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, r, http.StatusOK, "history.html", data)
+}
+
+// SnippetDiff shows the changes between the ?from= and ?to= revisions.
+// Without parameters it compares the latest revision with the one before it.
+func (app *application) SnippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Revisions are sorted newest first.
+	to := revisions[0].Number
+	from := max(to-1, 1)
+
+	query := r.URL.Query()
+	if query.Has("from") {
+		from, err = strconv.Atoi(query.Get("from"))
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+	if query.Has("to") {
+		to, err = strconv.Atoi(query.Get("to"))
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	fromRevision, err := app.snippets.Revision(snippet.ID, from)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	toRevision, err := app.snippets.Revision(snippet.ID, to)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+	data.From = fromRevision
+	data.To = toRevision
+	data.FileDiffs, err = diffRevisions(fromRevision, toRevision)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, http.StatusOK, "diff.html", data)
+}
+
+// SnippetRestorePost brings a snippet back to one of its revisions, files and language included. The restore is a
+// new revision, so it shows up in the history and can itself be undone.
+func (app *application) SnippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil || number < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = app.snippets.Restore(snippet.ID, number)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Revision #%d has been restored.", number))
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// ownedSnippet fetches the snippet from the {id} path value and makes sure it belongs to the current user.
+// It writes a 404 or 403 response itself and returns false when the handler should stop. The private snippets of other
+// users get a 404 like in SnippetView, so that their existence isn't revealed.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+func (app *application) SnippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   snippet.Language,
+		Filename:   snippet.Filename,
+		Files:      fileFields(snippet.Files),
+		Expires:    models.KeepExpires,
+		Visibility: snippet.Visibility,
+		Tags:       strings.Join(snippet.Tags, ", "),
+		editing:    true,
+	}
+
+	app.render(w, r, http.StatusOK, "create.html", data)
+}
+
+func (app *application) SnippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	form, err := parseSnippetForm(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.editing = true
+
+	if form.AddFile {
+		form.Files = append(form.Files, form.NewFile())
+
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusOK, "create.html", data)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "create.html", data)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+func (app *application) SnippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 // About pages
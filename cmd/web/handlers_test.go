@@ -3,9 +3,11 @@ package main
 import (
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"snippetbox.hichammou/internal/assert"
+	"snippetbox.hichammou/internal/models"
 )
 
 func TestPing(t *testing.T) {
@@ -39,29 +41,1624 @@ func TestSnippetView(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantBody: "An old silent pond...",
 		},
+		{
+			name:     "Shows author",
+			urlPath:  "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "By <a href='/u/1'>Hicham</a>",
+		},
 		{
 			name:     "Non-existent ID",
 			urlPath:  "/snippet/view/2",
 			wantCode: http.StatusNotFound,
 		},
 		{
-			name:     "Negative ID",
-			urlPath:  "/snippet/view/-1",
-			wantCode: http.StatusInternalServerError,
+			name:     "Highlighted content",
+			urlPath:  "/snippet/view/3",
+			wantCode: http.StatusOK,
+			wantBody: `<span class="hl-literal">true</span>`,
+		},
+		{
+			name:     "Extra file",
+			urlPath:  "/snippet/view/3",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='file-name'>Dockerfile</span>",
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/view/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Last view of a burn after reading snippet",
+			urlPath:  "/snippet/view/5",
+			wantCode: http.StatusOK,
+			wantBody: "burned, this was the last view",
+		},
+		{
+			name:     "Negative ID",
+			urlPath:  "/snippet/view/-1",
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Decimal ID",
+			urlPath:  "/snippet/view/1.23",
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/snippet/view/foo",
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Empty ID",
+			urlPath:  "/snippet/view/",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetRaw(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		urlPath         string
+		wantCode        int
+		wantBody        string
+		wantDisposition string
+	}{
+		{
+			name:     "Raw",
+			urlPath:  "/snippet/raw/1",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+		},
+		{
+			name:     "Second file",
+			urlPath:  "/snippet/raw/3?file=Dockerfile",
+			wantCode: http.StatusOK,
+			wantBody: "FROM golang:1.23",
+		},
+		{
+			name:     "First file by name",
+			urlPath:  "/snippet/raw/3?file=main.go",
+			wantCode: http.StatusOK,
+			wantBody: "Over the wintry forest := true",
+		},
+		{
+			name:     "Non-existent file",
+			urlPath:  "/snippet/raw/3?file=Makefile",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/raw/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/raw/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burn after reading",
+			urlPath:  "/snippet/raw/5",
+			wantCode: http.StatusOK,
+			wantBody: "This message will self-destruct",
+		},
+		{
+			name:     "Protected snippet",
+			urlPath:  "/snippet/raw/6",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:            "Download named after the title",
+			urlPath:         "/snippet/download/1",
+			wantCode:        http.StatusOK,
+			wantBody:        "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+			wantDisposition: "attachment; filename=an-old-silent-pond.txt",
+		},
+		{
+			name:            "Download of a named file",
+			urlPath:         "/snippet/download/3?file=Dockerfile",
+			wantCode:        http.StatusOK,
+			wantBody:        "FROM golang:1.23",
+			wantDisposition: "attachment; filename=Dockerfile",
+		},
+		{
+			name:     "Download of a protected snippet",
+			urlPath:  "/snippet/download/6",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, body, tt.wantBody)
+				assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+				assert.Equal(t, headers.Get("X-Content-Type-Options"), "nosniff")
+				assert.Equal(t, headers.Get("Content-Disposition"), tt.wantDisposition)
+			}
+		})
+	}
+}
+
+func TestSnippetDiff(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Latest changes",
+			urlPath:  "/snippet/view/1/diff",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='diff-insert'>&#43;An old silent pond...</span>",
+		},
+		{
+			name:     "Added file",
+			urlPath:  "/snippet/view/1/diff",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='file-name'>notes.txt</span> added",
+		},
+		{
+			name:     "Explicit revisions",
+			urlPath:  "/snippet/view/1/diff?from=2&to=1",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='diff-delete'>-An old silent pond...</span>",
+		},
+		{
+			name:     "Too different",
+			urlPath:  "/snippet/view/3/diff",
+			wantCode: http.StatusOK,
+			wantBody: "This file is too different between the revisions to diff.",
+		},
+		{
+			name:     "Non-existent revision",
+			urlPath:  "/snippet/view/1/diff?from=1&to=9",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid revision",
+			urlPath:  "/snippet/view/1/diff?from=foo",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "History",
+			urlPath:  "/snippet/view/1/history",
+			wantCode: http.StatusOK,
+			wantBody: "/snippet/view/1/diff?from=1&to=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetUnlockPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	const content = "The treasure is under the old oak"
+
+	code, _, body := ts.get(t, "/snippet/view/6")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<form action='/snippet/unlock/6' method='POST' class='unlock' novalidate>")
+	assert.Equal(t, strings.Contains(body, content), false)
+
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		password string
+		wantCode int
+	}{
+		{
+			name:     "Empty passphrase",
+			password: "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Wrong passphrase",
+			password: "let me in",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Right passphrase",
+			password: "open sesame",
+			wantCode: http.StatusSeeOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("password", tt.password)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.PostForm(t, "/snippet/unlock/6", form)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+
+	// The unlock is remembered in the session, so the content now shows up.
+	code, _, body = ts.get(t, "/snippet/view/6")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, content)
+}
+
+func TestTagView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Known tag",
+			urlPath:  "/tags/haiku",
+			wantBody: "<a href=\"/snippet/view/1\">An old silent pond</a>",
+		},
+		{
+			name:     "Upper case tag",
+			urlPath:  "/tags/HAIKU",
+			wantBody: "<a href=\"/snippet/view/1\">An old silent pond</a>",
+		},
+		{
+			name:     "Next page",
+			urlPath:  "/tags/haiku",
+			wantBody: "<a class='next' href='/tags/haiku?sort=created&order=desc&after=",
+		},
+		{
+			name:     "Unknown tag",
+			urlPath:  "/tags/unknown",
+			wantBody: "There's no snippet with this tag yet!",
+		},
+		{
+			name:     "Invalid cursor",
+			urlPath:  "/tags/haiku?after=foo",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			if tt.wantCode == 0 {
+				tt.wantCode = http.StatusOK
+			}
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	next := models.Cursor{Sort: models.SortTitle, Key: "An old silent pond", ID: 1}.Encode()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "First page",
+			urlPath:  "/snippets",
+			wantCode: http.StatusOK,
+			wantBody: "<a href=\"/snippet/view/1\">An old silent pond</a>",
+		},
+		{
+			name:     "Next link",
+			urlPath:  "/snippets?sort=title",
+			wantCode: http.StatusOK,
+			wantBody: "<a class='next' href='/snippets?sort=title&order=asc&after=" + next + "'>",
+		},
+		{
+			name:     "Last page",
+			urlPath:  "/snippets?sort=title&after=" + next,
+			wantCode: http.StatusOK,
+			wantBody: "There's nothing to see here yet!",
+		},
+		{
+			name:     "Cursor of another sort",
+			urlPath:  "/snippets?sort=expires&after=" + next,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown sort",
+			urlPath:  "/snippets?sort=views",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown order",
+			urlPath:  "/snippets?order=random",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSearchView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantBody string
+	}{
+		{
+			name:     "Empty query",
+			urlPath:  "/search",
+			wantBody: "<input type='search' name='q' value='' placeholder='Search snippets'>",
+		},
+		{
+			name:     "Matching term",
+			urlPath:  "/search?q=pond",
+			wantBody: "<p class='excerpt'>An old silent <mark>pond</mark>...",
+		},
+		{
+			name:     "Tag filter",
+			urlPath:  "/search?q=tag:haiku",
+			wantBody: "<a href='/snippet/view/1'>An old silent pond</a>",
+		},
+		{
+			name:     "No match",
+			urlPath:  "/search?q=wintry",
+			wantBody: "No snippet matches your search.",
+		},
+		{
+			name:     "Query kept in the form",
+			urlPath:  "/search?q=%22old+pond%22",
+			wantBody: "value='&#34;old pond&#34;'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, http.StatusOK)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestSnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		status, header, _ := ts.get(t, "/snippet/create")
+
+		location := header.Get("location")
+
+		assert.Equal(t, status, http.StatusSeeOther)
+		assert.Equal(t, location, "/user/login")
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		_, _, body := ts.get(t, "/user/login")
+
+		csrfToken := extractCSRFToken(t, body)
+
+		form := url.Values{}
+		form.Add("email", "hicham@gmail.com")
+		form.Add("password", "1234")
+		form.Add("csrf_token", csrfToken)
+
+		ts.PostForm(t, "/user/login", form)
+
+		code, _, body := ts.get(t, "/snippet/create")
+
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/snippet/create' method='POST'>")
+	})
+}
+
+func TestSnippetCreatePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	const (
+		dockerfile = "FROM golang:1.23"
+		config     = "port: 4000"
+	)
+
+	tests := []struct {
+		name         string
+		filename     string
+		files        [][3]string
+		addFile      bool
+		password     string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Single file",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/2",
+		},
+		{
+			name:         "Several files",
+			filename:     "Dockerfile",
+			files:        [][3]string{{"config.yaml", "yaml", config}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/2",
+		},
+		{
+			name:         "Empty file is dropped",
+			files:        [][3]string{{"", "auto", ""}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/2",
+		},
+		{
+			name:     "Add a file",
+			filename: "Dockerfile",
+			files:    [][3]string{{"config.yaml", "yaml", config}},
+			addFile:  true,
+			wantCode: http.StatusOK,
+			wantBody: "<input type='text' name='file_name' value=''",
+		},
+		{
+			name:     "First file without a name",
+			files:    [][3]string{{"config.yaml", "yaml", config}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Files need a name when a snippet has more than one",
+		},
+		{
+			name:     "Duplicate name",
+			filename: "Dockerfile",
+			files:    [][3]string{{"Dockerfile", "", config}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Another file already has this name",
+		},
+		{
+			name:     "Name with a slash",
+			filename: "Dockerfile",
+			files:    [][3]string{{"etc/config.yaml", "yaml", config}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "A file name can&#39;t contain slashes",
+		},
+		{
+			name:     "Empty content",
+			filename: "Dockerfile",
+			files:    [][3]string{{"config.yaml", "yaml", " "}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This file can&#39;t be empty",
+		},
+		{
+			name:     "File too large",
+			filename: "Dockerfile",
+			files:    [][3]string{{"config.yaml", "yaml", strings.Repeat("a", 65536)}},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "A file can&#39;t be larger than 64 KB",
+		},
+		{
+			name:     "Files too large together",
+			filename: "Dockerfile",
+			files: [][3]string{
+				{"a.txt", "", strings.Repeat("a", 65535)},
+				{"b.txt", "", strings.Repeat("b", 65535)},
+				{"c.txt", "", strings.Repeat("c", 65535)},
+				{"d.txt", "", strings.Repeat("d", 65535)},
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "The files of a snippet can&#39;t add up to more than 256 KB",
+		},
+		{
+			name:         "Passphrase of 72 bytes",
+			password:     strings.Repeat("é", 36),
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/2",
+		},
+		{
+			// 40 characters, but bcrypt would only use the first 72 of its 80 bytes.
+			name:     "Passphrase over 72 bytes",
+			password: strings.Repeat("é", 40),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field can&#39;t be more than 72 bytes long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+			form.Add("title", "Web service")
+			form.Add("filename", tt.filename)
+			form.Add("content", dockerfile)
+			form.Add("language", "auto")
+			form.Add("expires", "7")
+			form.Add("visibility", "public")
+			form.Add("password", tt.password)
+			for _, f := range tt.files {
+				form.Add("file_name", f[0])
+				form.Add("file_language", f[1])
+				form.Add("file_content", f[2])
+			}
+			if tt.addFile {
+				form.Add("add_file", "Add a file")
+			}
+
+			code, headers, body := ts.PostForm(t, "/snippet/create", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			// The two files posted, the one added and the blank one the script clones.
+			if tt.addFile {
+				assert.Equal(t, strings.Count(body, "<fieldset class='file'>"), 3)
+			}
+		})
+	}
+
+	t.Run("Mismatched file fields", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+		form.Add("title", "Web service")
+		form.Add("content", dockerfile)
+		form.Add("expires", "7")
+		form.Add("visibility", "public")
+		form.Add("file_name", "config.yaml")
+
+		code, _, _ := ts.PostForm(t, "/snippet/create", form)
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+}
+
+func TestPastePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid paste",
+			urlPath:  "/p",
+			token:    validToken,
+			body:     "go: downloading golang.org/x/crypto v0.24.0",
+			wantCode: http.StatusCreated,
+			wantBody: ts.URL + "/snippet/view/2",
+		},
+		{
+			name:     "Settings in the query string",
+			urlPath:  "/p?title=Build+log&language=&expires=1&visibility=private",
+			token:    validToken,
+			body:     "ok",
+			wantCode: http.StatusCreated,
+			wantBody: ts.URL + "/snippet/view/2",
+		},
+		{
+			name:     "No token",
+			urlPath:  "/p",
+			body:     "ok",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Unknown token",
+			urlPath:  "/p",
+			token:    "NOTATOKEN",
+			body:     "ok",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Read token",
+			urlPath:  "/p",
+			token:    readToken,
+			body:     "ok",
+			wantCode: http.StatusForbidden,
+			wantBody: "this token is read-only",
+		},
+		{
+			name:     "Empty body",
+			urlPath:  "/p",
+			token:    validToken,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "content: This field can't be empty",
+		},
+		{
+			name:     "Body too large",
+			urlPath:  "/p",
+			token:    validToken,
+			body:     strings.Repeat("a", 65536),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "content: A file can't be larger than 64 KB",
+		},
+		{
+			name:     "Invalid expiry",
+			urlPath:  "/p?expires=3",
+			token:    validToken,
+			body:     "ok",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "expires: This field must equal to 1, 7 or 365",
+		},
+		{
+			name:     "Non-numeric expiry",
+			urlPath:  "/p?expires=soon",
+			token:    validToken,
+			body:     "ok",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unsupported language",
+			urlPath:  "/p?language=cobol",
+			token:    validToken,
+			body:     "ok",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "language: This language isn't supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.request(t, http.MethodPost, tt.urlPath, bearer(tt.token), tt.body)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.Equal(t, body, tt.wantBody)
+			}
+			if code == http.StatusCreated {
+				assert.Equal(t, headers.Get("Location"), tt.wantBody)
+			}
+			if code == http.StatusUnauthorized {
+				assert.Equal(t, headers.Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestUserTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/user/account/tokens")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	ts.login(t)
+
+	t.Run("List", func(t *testing.T) {
+		code, _, body := ts.get(t, "/user/account/tokens")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<td>build server</td>")
+		assert.StringContains(t, body, "<td>dashboard</td>")
+		assert.StringContains(t, body, "<form action='/user/account/tokens/revoke/2' method='POST'>")
+	})
+
+	tests := []struct {
+		name      string
+		tokenName string
+		scope     string
+		expires   string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:      "Valid token",
+			tokenName: "build server",
+			scope:     "write",
+			expires:   "90",
+			wantCode:  http.StatusOK,
+			wantBody:  "<pre class='token'><code>MOCKTOKENFORTHEMOCKUSER</code></pre>",
+		},
+		{
+			name:      "Token that never expires",
+			tokenName: "dashboard",
+			scope:     "read",
+			expires:   "0",
+			wantCode:  http.StatusOK,
+			wantBody:  "<pre class='token'><code>MOCKTOKENFORTHEMOCKUSER</code></pre>",
+		},
+		{
+			name:     "Empty name",
+			scope:    "write",
+			expires:  "90",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field can&#39;t be empty",
+		},
+		{
+			name:      "Invalid scope",
+			tokenName: "build server",
+			scope:     "admin",
+			expires:   "90",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantBody:  "This field must be read or write",
+		},
+		{
+			name:      "Invalid expiry",
+			tokenName: "build server",
+			scope:     "write",
+			expires:   "7",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantBody:  "This field must equal to 0, 30, 90 or 365",
+		},
+		{
+			name:      "Non-numeric expiry",
+			tokenName: "build server",
+			scope:     "write",
+			expires:   "soon",
+			wantCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/user/account/tokens"))
+			form.Add("name", tt.tokenName)
+			form.Add("scope", tt.scope)
+			form.Add("expires", tt.expires)
+
+			code, _, body := ts.PostForm(t, "/user/account/tokens", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestUserTokenRevokePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Own token",
+			urlPath:      "/user/account/tokens/revoke/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/user/account/tokens",
+		},
+		{
+			name:     "Someone else's token",
+			urlPath:  "/user/account/tokens/revoke/3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid ID",
+			urlPath:  "/user/account/tokens/revoke/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/user/account/tokens"))
+
+			code, headers, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestSnippetEdit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/snippet/edit/1")
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("location"), "/user/login")
+	})
+
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Owner",
+			urlPath:  "/snippet/edit/1",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/edit/1' method='POST'>",
+		},
+		{
+			name:     "Not the owner",
+			urlPath:  "/snippet/edit/3",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/edit/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/edit/2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetEditPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	// The form keeps the current expiry date unless another lifetime is chosen.
+	_, _, body := ts.get(t, "/snippet/edit/1")
+	assert.StringContains(t, body, "<input type='radio' name='expires' value='0' checked> Keep current")
+
+	tests := []struct {
+		name     string
+		expires  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Keep current expiry",
+			expires:  "0",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "New expiry",
+			expires:  "365",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Invalid expiry",
+			expires:  "30",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must equal to 1, 7 or 365",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "An old silent pond")
+			form.Add("content", "An old silent pond...")
+			form.Add("expires", tt.expires)
+			form.Add("visibility", "public")
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/edit/1"))
+
+			code, headers, body := ts.PostForm(t, "/snippet/edit/1", form)
+			assert.Equal(t, code, tt.wantCode)
+			if code == http.StatusSeeOther {
+				assert.Equal(t, headers.Get("Location"), "/snippet/view/1")
+			}
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+
+	// Creating a snippet still needs a lifetime.
+	t.Run("Keep current on create", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("title", "O snail")
+		form.Add("content", "O snail")
+		form.Add("expires", "0")
+		form.Add("visibility", "public")
+		form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+
+		code, _, _ := ts.PostForm(t, "/snippet/create", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	})
+}
+
+func TestSnippetFork(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/snippet/fork/3")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Pre-filled form",
+			urlPath:  "/snippet/fork/3",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/fork/3' method='POST'>",
+		},
+		{
+			name:     "Attribution",
+			urlPath:  "/snippet/fork/3",
+			wantCode: http.StatusOK,
+			wantBody: "Forking <a href='/snippet/view/3'>#3 Over the wintry forest</a> by Alice",
+		},
+		{
+			name:     "Pre-filled files",
+			urlPath:  "/snippet/fork/3",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='text' name='file_name' value='Dockerfile'",
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/fork/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Fork count",
+			urlPath:  "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='fork'>2 forks</span>",
+		},
+		{
+			name:     "Forked from",
+			urlPath:  "/snippet/view/3",
+			wantCode: http.StatusOK,
+			wantBody: "<span class='fork'>forked from <a href='/snippet/view/1'>#1</a></span>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetForkPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		title        string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Valid fork",
+			urlPath:      "/snippet/fork/3",
+			title:        "My wintry forest",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/2",
+		},
+		{
+			name:     "Empty title",
+			urlPath:  "/snippet/fork/3",
+			title:    "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/fork/4",
+			title:    "Stolen",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/fork/3"))
+			form.Add("title", tt.title)
+			form.Add("content", "Over the wintry forest := false")
+			form.Add("language", "go")
+			form.Add("expires", "7")
+			form.Add("visibility", "public")
+
+			code, headers, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestSnippetStarPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+		code, headers, _ := ts.PostForm(t, "/snippet/star/1", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	ts.login(t)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		csrfToken    string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Star",
+			urlPath:      "/snippet/star/1",
+			csrfToken:    ts.csrfToken(t, "/snippet/view/1"),
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1",
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/star/4",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid CSRF token",
+			urlPath:   "/snippet/star/1",
+			csrfToken: "wrongToken",
+			wantCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.csrfToken
+			if token == "" {
+				token = ts.csrfToken(t, "/snippet/view/1")
+			}
+
+			form := url.Values{}
+			form.Add("csrf_token", token)
+
+			code, headers, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+		})
+	}
+
+	t.Run("Star button", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/view/1")
+		assert.StringContains(t, body, "<button>Star</button>")
+
+		_, _, body = ts.get(t, "/snippet/view/3")
+		assert.StringContains(t, body, "<button>Unstar</button>")
+		assert.StringContains(t, body, "<span class='stars'>1 star</span>")
+	})
+}
+
+func TestUserStars(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/user/stars")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<a href=\"/snippet/view/3\">Over the wintry forest</a>")
+}
+
+func TestSnippetViewLines(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+		dontWant string
+	}{
+		{
+			name:     "Line numbers",
+			urlPath:  "/snippet/view/1",
+			wantCode: http.StatusOK,
+			wantBody: "<td class='gutter'><a href='#L3'>3</a></td>",
+		},
+		{
+			name:     "Slice",
+			urlPath:  "/snippet/view/1?lines=2-3",
+			wantCode: http.StatusOK,
+			wantBody: "Showing lines 2 to 3 of 3.",
+			dontWant: "An old silent pond...",
+		},
+		{
+			name:     "Slice past the end",
+			urlPath:  "/snippet/view/1?lines=L3-L10",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/snippet/view/1#L3'>Show all lines</a>",
+			dontWant: "A frog jumps into the pond,",
+		},
+		{
+			name:     "Start past the end",
+			urlPath:  "/snippet/view/1?lines=4",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Malformed range",
+			urlPath:  "/snippet/view/1?lines=3-2",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Start past the end of a burn after reading snippet",
+			urlPath:  "/snippet/view/5?lines=2",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "The view was left for a valid range",
+			urlPath:  "/snippet/view/5?lines=1",
+			wantCode: http.StatusOK,
+			wantBody: "burned, this was the last view",
+		},
+		{
+			name:     "Burned",
+			urlPath:  "/snippet/view/5",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+			if tt.dontWant != "" && strings.Contains(body, tt.dontWant) {
+				t.Errorf("body contains %q", tt.dontWant)
+			}
+		})
+	}
+}
+
+func TestSnippetComments(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/snippet/view/1")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<p>What a lovely haiku!</p>")
+	assert.StringContains(t, body, "<div class='reply'>")
+	assert.StringContains(t, body, "<p>Thanks, I wrote it last spring.</p>")
+	assert.StringContains(t, body, "<a href='/user/login'>Log in</a> to join the discussion.")
+
+	// Alice's note is shown right under the line it is attached to, not with the general comments.
+	assert.StringContains(t, body, "<tr id='L1' class='noted'>")
+	assert.StringContains(t, body, "<div class='note-range'>Line 1</div>")
+	assert.StringContains(t, body, "<p>Shouldn&#39;t the pond be still?</p>")
+}
+
+func TestSnippetCommentPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		content      string
+		parent       string
+		lines        string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "New thread",
+			urlPath:      "/snippet/comment/1",
+			content:      "Beautiful",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1#comment-3",
+		},
+		{
+			name:         "Reply to a reply",
+			urlPath:      "/snippet/comment/1",
+			content:      "Indeed",
+			parent:       "2",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1#comment-3",
+		},
+		{
+			name:         "Inline note",
+			urlPath:      "/snippet/comment/1",
+			content:      "Nice line",
+			lines:        "L1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1#comment-3",
+		},
+		{
+			name:     "Lines out of range",
+			urlPath:  "/snippet/comment/1",
+			content:  "Nice lines",
+			lines:    "1-5",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Lines backwards",
+			urlPath:  "/snippet/comment/1",
+			content:  "Nice lines",
+			lines:    "1-0",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Parent on another snippet",
+			urlPath:  "/snippet/comment/3",
+			content:  "Lost",
+			parent:   "1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown parent",
+			urlPath:  "/snippet/comment/1",
+			content:  "Lost",
+			parent:   "99",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty comment",
+			urlPath:  "/snippet/comment/1",
+			content:  "  ",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Too long",
+			urlPath:  "/snippet/comment/1",
+			content:  strings.Repeat("a", 1001),
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/comment/4",
+			content:  "Hello",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/view/1"))
+			form.Add("content", tt.content)
+			form.Add("parent", tt.parent)
+			form.Add("lines", tt.lines)
+
+			code, headers, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestCommentEdit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		urlPath  string
+		content  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Edit form",
+			method:   http.MethodGet,
+			urlPath:  "/comment/edit/1",
+			wantCode: http.StatusOK,
+			wantBody: "<textarea name='content'>What a lovely haiku!</textarea>",
+		},
+		{
+			name:     "Someone else's comment",
+			method:   http.MethodGet,
+			urlPath:  "/comment/edit/2",
+			wantCode: http.StatusForbidden,
 		},
 		{
-			name:     "Decimal ID",
-			urlPath:  "/snippet/view/1.23",
-			wantCode: http.StatusInternalServerError,
+			name:     "Unknown comment",
+			method:   http.MethodGet,
+			urlPath:  "/comment/edit/99",
+			wantCode: http.StatusNotFound,
 		},
 		{
-			name:     "String ID",
-			urlPath:  "/snippet/view/foo",
-			wantCode: http.StatusInternalServerError,
+			name:     "Save",
+			method:   http.MethodPost,
+			urlPath:  "/comment/edit/1",
+			content:  "What a lovely haiku! Edit: typo",
+			wantCode: http.StatusSeeOther,
 		},
 		{
-			name:     "Empty ID",
-			urlPath:  "/snippet/view/",
+			name:     "Save empty",
+			method:   http.MethodPost,
+			urlPath:  "/comment/edit/1",
+			content:  "",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field can&#39;t be empty",
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodPost,
+			urlPath:  "/comment/delete/1",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Delete someone else's comment",
+			method:   http.MethodPost,
+			urlPath:  "/comment/delete/2",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			var body string
+
+			if tt.method == http.MethodGet {
+				code, _, body = ts.get(t, tt.urlPath)
+			} else {
+				form := url.Values{}
+				form.Add("csrf_token", ts.csrfToken(t, "/snippet/view/1"))
+				form.Add("content", tt.content)
+
+				code, _, body = ts.PostForm(t, tt.urlPath, form)
+			}
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetDeletePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{
+			name:     "Owner",
+			urlPath:  "/snippet/delete/1",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Not the owner",
+			urlPath:  "/snippet/delete/3",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/delete/4",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/view/1"))
+
+			code, _, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestSnippetRestorePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/snippet/view/1/history")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<form action='/snippet/restore/1/1' method='POST'>")
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{
+			name:     "Owner",
+			urlPath:  "/snippet/restore/1/1",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Non-existent revision",
+			urlPath:  "/snippet/restore/1/9",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid revision",
+			urlPath:  "/snippet/restore/1/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/view/1"))
+
+			code, headers, _ := ts.PostForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			if code == http.StatusSeeOther {
+				assert.Equal(t, headers.Get("Location"), "/snippet/view/1")
+			}
+		})
+	}
+
+	t.Run("Not the owner", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/view/3/history")
+		assert.Equal(t, strings.Contains(body, "/snippet/restore/"), false)
+
+		form := url.Values{}
+		form.Add("csrf_token", ts.csrfToken(t, "/snippet/view/1"))
+
+		code, _, _ := ts.PostForm(t, "/snippet/restore/3/1", form)
+		assert.Equal(t, code, http.StatusForbidden)
+	})
+}
+
+func TestUserProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "By handle",
+			urlPath:  "/u/hicham",
+			wantCode: http.StatusOK,
+			wantBody: "<h2>Hicham <span class='handle'>@hicham</span></h2>",
+		},
+		{
+			name:     "By ID",
+			urlPath:  "/u/1",
+			wantCode: http.StatusOK,
+			wantBody: "<p class='bio'>Writes haikus in Go.</p>",
+		},
+		{
+			name:     "Public snippets",
+			urlPath:  "/u/Hicham",
+			wantCode: http.StatusOK,
+			wantBody: "<a href=\"/snippet/view/1\">An old silent pond</a>",
+		},
+		{
+			name:     "Next page",
+			urlPath:  "/u/1",
+			wantCode: http.StatusOK,
+			wantBody: "<a class='next' href='/u/hicham?sort=created&order=desc&after=",
+		},
+		{
+			name:     "Invalid cursor",
+			urlPath:  "/u/hicham?after=foo",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown handle",
+			urlPath:  "/u/nobody",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unknown ID",
+			urlPath:  "/u/2",
 			wantCode: http.StatusNotFound,
 		},
 	}
@@ -70,6 +1667,7 @@ func TestSnippetView(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			code, _, body := ts.get(t, tt.urlPath)
 			assert.Equal(t, code, tt.wantCode)
+
 			if tt.wantBody != "" {
 				assert.StringContains(t, body, tt.wantBody)
 			}
@@ -77,37 +1675,128 @@ func TestSnippetView(t *testing.T) {
 	}
 }
 
-func TestSnippetCreate(t *testing.T) {
+func TestUserSnippets(t *testing.T) {
 	app := newTestApplication(t)
 	ts := newTestServer(t, app.routes())
 	defer ts.Close()
 
 	t.Run("Unauthenticated", func(t *testing.T) {
-		status, header, _ := ts.get(t, "/snippet/create")
+		code, headers, _ := ts.get(t, "/user/snippets")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
 
-		location := header.Get("location")
+	ts.login(t)
 
-		assert.Equal(t, status, http.StatusSeeOther)
-		assert.Equal(t, location, "/user/login")
+	t.Run("Authenticated", func(t *testing.T) {
+		code, _, body := ts.get(t, "/user/snippets")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<a href='/snippet/view/1'>An old silent pond</a>")
+		assert.StringContains(t, body, "<tr class='expired'>")
+		assert.StringContains(t, body, "<span class='badge expired'>expired</span>")
+		assert.StringContains(t, body, "<span class='badge'>private</span>")
+		assert.StringContains(t, body, "<a class='next' href='/user/snippets?sort=created&order=desc&after=")
 	})
 
-	t.Run("Authenticated", func(t *testing.T) {
-		_, _, body := ts.get(t, "/user/login")
+	t.Run("Invalid cursor", func(t *testing.T) {
+		code, _, _ := ts.get(t, "/user/snippets?after=foo")
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+}
 
-		csrfToken := extractCSRFToken(t, body)
+func TestUserSnippetsPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
 
-		form := url.Values{}
-		form.Add("email", "hicham@gmail.com")
-		form.Add("password", "1234")
-		form.Add("csrf_token", csrfToken)
+	ts.login(t)
 
-		ts.PostForm(t, "/user/login", form)
+	tests := []struct {
+		name       string
+		ids        []string
+		action     string
+		expires    string
+		visibility string
+		wantCode   int
+		wantFlash  string
+		wantBody   string
+	}{
+		{
+			name:      "Delete",
+			ids:       []string{"1", "7"},
+			action:    "delete",
+			wantCode:  http.StatusSeeOther,
+			wantFlash: "2 snippets deleted.",
+		},
+		{
+			name:      "Someone else's snippet is left alone",
+			ids:       []string{"1", "3"},
+			action:    "extend",
+			expires:   "365",
+			wantCode:  http.StatusSeeOther,
+			wantFlash: "1 snippet extended.",
+		},
+		{
+			name:       "Change visibility",
+			ids:        []string{"7"},
+			action:     "visibility",
+			visibility: "unlisted",
+			wantCode:   http.StatusSeeOther,
+			wantFlash:  "1 snippet made unlisted.",
+		},
+		{
+			name:     "Nothing selected",
+			action:   "delete",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Select at least one snippet",
+		},
+		{
+			name:     "Unknown action",
+			ids:      []string{"1"},
+			action:   "publish",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This action is not supported",
+		},
+		{
+			name:     "Invalid expiry",
+			ids:      []string{"1"},
+			action:   "extend",
+			expires:  "30",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must equal 1, 7 or 365",
+		},
+		{
+			name:     "Invalid ID",
+			ids:      []string{"one"},
+			action:   "delete",
+			wantCode: http.StatusBadRequest,
+		},
+	}
 
-		code, _, body := ts.get(t, "/snippet/create")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", ts.csrfToken(t, "/user/snippets"))
+			form.Add("action", tt.action)
+			form.Add("expires", tt.expires)
+			form.Add("visibility", tt.visibility)
+			for _, id := range tt.ids {
+				form.Add("id", id)
+			}
 
-		assert.Equal(t, code, http.StatusOK)
-		assert.StringContains(t, body, "<form action='/snippet/create' method='POST'>")
-	})
+			code, _, body := ts.PostForm(t, "/user/snippets", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			if tt.wantFlash != "" {
+				_, _, body := ts.get(t, "/user/snippets")
+				assert.StringContains(t, body, tt.wantFlash)
+			}
+		})
+	}
 }
 
 func TestUserSignup(t *testing.T) {
@@ -122,6 +1811,7 @@ func TestUserSignup(t *testing.T) {
 
 	const (
 		validName     = "Bob"
+		validHandle   = "bob"
 		validPassword = "validPa$$word"
 		validEmail    = "bob@example.com"
 		formTag       = "<form action='/user/signup' method='POST' novalidate>"
@@ -130,6 +1820,7 @@ func TestUserSignup(t *testing.T) {
 	tests := []struct {
 		name         string
 		userName     string
+		userHandle   string
 		userEmail    string
 		userPassword string
 		csrfToken    string
@@ -139,6 +1830,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Valid submission",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    csrfToken,
@@ -147,6 +1839,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Invalid CSRF Token",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    "wrongToken",
@@ -155,6 +1848,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty name",
 			userName:     "",
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    csrfToken,
@@ -164,6 +1858,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "",
 			userPassword: validPassword,
 			csrfToken:    csrfToken,
@@ -173,6 +1868,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty password",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: "",
 			csrfToken:    csrfToken,
@@ -182,6 +1878,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Invalid email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "bob@example.",
 			userPassword: validPassword,
 			csrfToken:    csrfToken,
@@ -191,15 +1888,47 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Short password",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: "pa$$",
 			csrfToken:    csrfToken,
 			wantCode:     http.StatusUnprocessableEntity,
 			wantFormTag:  formTag,
 		},
+		{
+			name:         "Empty handle",
+			userName:     validName,
+			userHandle:   "",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    csrfToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Handle starting with a digit",
+			userName:     validName,
+			userHandle:   "2bob",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    csrfToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Duplicate handle",
+			userName:     validName,
+			userHandle:   "hicham",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    csrfToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
 		{
 			name:         "Duplicate email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "hicham@gmail.com",
 			userPassword: validPassword,
 			csrfToken:    csrfToken,
@@ -212,6 +1941,7 @@ func TestUserSignup(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			form := url.Values{}
 			form.Add("name", tt.userName)
+			form.Add("handle", tt.userHandle)
 			form.Add("email", tt.userEmail)
 			form.Add("password", tt.userPassword)
 			form.Add("csrf_token", tt.csrfToken)
@@ -2,12 +2,23 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
+	"regexp"
 	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/justinas/nosurf"
+	"snippetbox.hichammou/internal/diff"
+	"snippetbox.hichammou/internal/highlight"
+	"snippetbox.hichammou/internal/models"
 )
 
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
@@ -30,6 +41,37 @@ func (app *application) clientError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
 
+// baseURL returns the scheme and host the request was made to, for the URLs handed out to scripts.
+func baseURL(r *http.Request) string {
+	if r.TLS == nil {
+		return "http://" + r.Host
+	}
+	return "https://" + r.Host
+}
+
+// invalidToken answers a request whose bearer token is missing or unknown, telling the client how to authenticate.
+func (app *application) invalidToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	app.tokenError(w, r, http.StatusUnauthorized, "invalid or missing authentication token")
+}
+
+// insufficientScope answers a request made with a read token to a route that needs a write token.
+func (app *application) insufficientScope(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="write"`)
+	app.tokenError(w, r, http.StatusForbidden, "this token is read-only")
+}
+
+// tokenError sends the errors of the token middleware. API clients get them in JSON, like every other API response,
+// the others in plain text.
+func (app *application) tokenError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		app.apiError(w, r, status, message)
+		return
+	}
+
+	http.Error(w, message, status)
+}
+
 func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data templateData) {
 	ts, ok := app.templateCache[page]
 
@@ -61,6 +103,7 @@ func (app *application) newTemplateData(r *http.Request) templateData {
 		CurrentYear:     time.Now().Year(),
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuthenticated: app.isAuthenticated(r),
+		UserID:          app.authenticatedUserID(r),
 		CSRFToken:       nosurf.Token(r),
 	}
 }
@@ -72,3 +115,325 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// authenticatedUserID returns the ID of the current user, or 0 if the request isn't authenticated.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(authenticatedUserIDContextKey).(int)
+	if !ok {
+		return 0
+	}
+	return id
+}
+
+// unlockedSessionKey is the session key that remembers the passphrase of a snippet was typed correctly.
+func unlockedSessionKey(snippetID int) string {
+	return fmt.Sprintf("unlockedSnippet:%d", snippetID)
+}
+
+// isUnlocked reports whether the content of the snippet can be shown to the current visitor. Snippets without a
+// passphrase are always unlocked, and so are the snippets of the current user.
+func (app *application) isUnlocked(r *http.Request, snippet models.Snippet) bool {
+	if !snippet.IsProtected() || snippet.UserID == app.authenticatedUserID(r) {
+		return true
+	}
+	return app.sessionManager.GetBool(r.Context(), unlockedSessionKey(snippet.ID))
+}
+
+// parseTags splits a comma separated list of tags. Tags are lower-cased, and blank or repeated ones are dropped.
+func parseTags(value string) []string {
+	var tags []string
+
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+var whitespaceRX = regexp.MustCompile(`\s+`)
+
+// excerpt returns a short extract of the content around the first occurrence of one of the words, with every occurrence
+// wrapped in a <mark> element. Without any match it returns the beginning of the content.
+func excerpt(content string, words []string, width int) template.HTML {
+	content = strings.TrimSpace(whitespaceRX.ReplaceAllString(content, " "))
+
+	var rx *regexp.Regexp
+	if len(words) > 0 {
+		quoted := make([]string, len(words))
+		for i, w := range words {
+			quoted[i] = regexp.QuoteMeta(w)
+		}
+		rx = regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	}
+
+	start := 0
+	if rx != nil {
+		if loc := rx.FindStringIndex(content); loc != nil {
+			// Keep a bit of the text before the match for context.
+			start = max(loc[0]-width/4, 0)
+		}
+	}
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+
+	end := min(start+width, len(content))
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	window := content[start:end]
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+
+	last := 0
+	if rx != nil {
+		for _, loc := range rx.FindAllStringIndex(window, -1) {
+			sb.WriteString(template.HTMLEscapeString(window[last:loc[0]]))
+			sb.WriteString("<mark>")
+			sb.WriteString(template.HTMLEscapeString(window[loc[0]:loc[1]]))
+			sb.WriteString("</mark>")
+			last = loc[1]
+		}
+	}
+	sb.WriteString(template.HTMLEscapeString(window[last:]))
+
+	if end < len(content) {
+		sb.WriteString("…")
+	}
+
+	return template.HTML(sb.String())
+}
+
+// purgeExpiredComments deletes the comments of expired snippets right away, then once every interval for as long as
+// the server runs.
+func (app *application) purgeExpiredComments(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := app.comments.DeleteExpired()
+		if err != nil {
+			app.logger.Error(err.Error())
+		} else if n > 0 {
+			app.logger.Info("purged the comments of expired snippets", "threads", n)
+		}
+
+		<-ticker.C
+	}
+}
+
+var lineRangeRX = regexp.MustCompile(`^L?(\d+)(?:-L?(\d+))?$`)
+
+// parseLineRange reads a line or a range of lines written as "7", "3-7" or "L3-L7", the way they appear in URL fragments.
+// It reports false when the value isn't a valid range of positive line numbers.
+func parseLineRange(s string) (int, int, bool) {
+	m := lineRangeRX.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+
+	start, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	end := start
+	if m[2] != "" {
+		end, err = strconv.Atoi(m[2])
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if start < 1 || end < start {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
+
+// codeLine is one line of a snippet as shown on its page, with the inline notes that end on it.
+type codeLine struct {
+	Number int
+	HTML   template.HTML
+	// Noted is set on the lines covered by at least one note.
+	Noted bool
+	Notes []noteGroup
+}
+
+// noteGroup gathers the threads attached to the same range of lines.
+type noteGroup struct {
+	Start   int
+	End     int
+	Threads []models.Comment
+}
+
+// annotate highlights the content line by line and places the inline notes under the last line of their range.
+// It returns the lines, and the general comments that aren't attached to any line.
+func annotate(language, content string, comments []models.Comment) ([]codeLine, []models.Comment) {
+	html := highlight.Lines(language, content)
+
+	lines := make([]codeLine, len(html))
+	for i, h := range html {
+		lines[i] = codeLine{Number: i + 1, HTML: h}
+	}
+
+	general := []models.Comment{}
+
+	for _, c := range comments {
+		if c.LineStart == 0 || len(lines) == 0 {
+			general = append(general, c)
+			continue
+		}
+
+		// The content may have been shortened since the note was written, it then sticks to the last line.
+		end := min(c.LineEnd, len(lines))
+		start := min(c.LineStart, end)
+
+		for i := start; i <= end; i++ {
+			lines[i-1].Noted = true
+		}
+
+		last := &lines[end-1]
+		if n := len(last.Notes); n > 0 && last.Notes[n-1].Start == c.LineStart && last.Notes[n-1].End == c.LineEnd {
+			last.Notes[n-1].Threads = append(last.Notes[n-1].Threads, c)
+		} else {
+			last.Notes = append(last.Notes, noteGroup{Start: c.LineStart, End: c.LineEnd, Threads: []models.Comment{c}})
+		}
+	}
+
+	return lines, general
+}
+
+// nonSlugRX matches the runs of characters that can't be part of a file name made from a title.
+var nonSlugRX = regexp.MustCompile(`[^a-z0-9]+`)
+
+// downloadName returns the name a file of a snippet is downloaded as. Named files keep their name, the others get one
+// made from the title of the snippet and the extension of their language, like "docker-compose-for-mysql.yaml".
+func downloadName(title string, file models.SnippetFile) string {
+	if file.Name != "" {
+		return file.Name
+	}
+
+	slug := strings.Trim(nonSlugRX.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == "" {
+		slug = "snippet"
+	}
+
+	return slug + highlight.Extension(file.Language)
+}
+
+// parsePageQuery reads the sort, order, after and before parameters of a listing. It returns false when one of them is
+// invalid, or when both cursors are given. The order is returned as given, or defaulted, for the links of the page.
+func parsePageQuery(query url.Values, limit int) (models.PageQuery, string, bool) {
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = models.SortCreated
+	}
+
+	// The newest snippets come first, but the ones about to expire and titles are more useful in ascending order.
+	order := query.Get("order")
+	if order == "" {
+		order = "asc"
+		if sort == models.SortCreated {
+			order = "desc"
+		}
+	}
+
+	if !models.ValidSort(sort) || (order != "asc" && order != "desc") {
+		return models.PageQuery{}, "", false
+	}
+
+	q := models.PageQuery{Sort: sort, Desc: order == "desc", Limit: limit}
+
+	for param, cursor := range map[string]**models.Cursor{"after": &q.After, "before": &q.Before} {
+		if value := query.Get(param); value != "" {
+			c, err := models.ParseCursor(value, sort)
+			if err != nil {
+				return models.PageQuery{}, "", false
+			}
+			*cursor = &c
+		}
+	}
+
+	if q.After != nil && q.Before != nil {
+		return models.PageQuery{}, "", false
+	}
+
+	return q, order, true
+}
+
+// fileDiff is the diff of a file between two revisions of a snippet. A file added or removed by the revision is
+// diffed against nothing.
+type fileDiff struct {
+	OldName      string
+	NewName      string
+	OldLanguage  string
+	NewLanguage  string
+	Added        bool
+	Removed      bool
+	Hunks        []diff.Hunk
+	TooDifferent bool
+}
+
+// diffRevisions diffs every file of two revisions, leaving out the files that didn't change. The first files are
+// always compared with each other, even when one was renamed, and the others are matched by name.
+func diffRevisions(from, to models.Revision) ([]fileDiff, error) {
+	oldFiles, newFiles := from.AllFiles(), to.AllFiles()
+
+	pairs := []fileDiff{{
+		OldName: oldFiles[0].Name, NewName: newFiles[0].Name,
+		OldLanguage: oldFiles[0].Language, NewLanguage: newFiles[0].Language,
+	}}
+	contents := [][2]string{{oldFiles[0].Content, newFiles[0].Content}}
+
+	matched := map[string]bool{}
+	for _, old := range oldFiles[1:] {
+		i := slices.IndexFunc(newFiles[1:], func(f models.SnippetFile) bool { return f.Name == old.Name })
+		if i < 0 {
+			pairs = append(pairs, fileDiff{OldName: old.Name, OldLanguage: old.Language, Removed: true})
+			contents = append(contents, [2]string{old.Content, ""})
+			continue
+		}
+
+		matched[old.Name] = true
+		f := newFiles[i+1]
+		pairs = append(pairs, fileDiff{OldName: old.Name, NewName: f.Name, OldLanguage: old.Language, NewLanguage: f.Language})
+		contents = append(contents, [2]string{old.Content, f.Content})
+	}
+	for _, f := range newFiles[1:] {
+		if !matched[f.Name] {
+			pairs = append(pairs, fileDiff{NewName: f.Name, NewLanguage: f.Language, Added: true})
+			contents = append(contents, [2]string{"", f.Content})
+		}
+	}
+
+	var diffs []fileDiff
+	for i, d := range pairs {
+		var err error
+		d.Hunks, err = diff.Unified(contents[i][0], contents[i][1], 3)
+		if err != nil {
+			if !errors.Is(err, diff.ErrTooDifferent) {
+				return nil, err
+			}
+			d.TooDifferent = true
+		}
+
+		if d.Hunks == nil && !d.TooDifferent && !d.Added && !d.Removed && d.OldName == d.NewName && d.OldLanguage == d.NewLanguage {
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+
+	return diffs, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+	"snippetbox.hichammou/internal/models"
+)
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart int
+		wantEnd   int
+		wantOK    bool
+	}{
+		{value: "7", wantStart: 7, wantEnd: 7, wantOK: true},
+		{value: "3-7", wantStart: 3, wantEnd: 7, wantOK: true},
+		{value: "L3-L7", wantStart: 3, wantEnd: 7, wantOK: true},
+		{value: "L3", wantStart: 3, wantEnd: 3, wantOK: true},
+		{value: "7-3"},
+		{value: "0"},
+		{value: "3-"},
+		{value: "three"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, end, ok := parseLineRange(tt.value)
+			assert.Equal(t, ok, tt.wantOK)
+			assert.Equal(t, start, tt.wantStart)
+			assert.Equal(t, end, tt.wantEnd)
+		})
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	comments := []models.Comment{
+		{ID: 1, Content: "General"},
+		{ID: 2, LineStart: 1, LineEnd: 2},
+		{ID: 3, LineStart: 1, LineEnd: 2},
+		{ID: 4, LineStart: 3, LineEnd: 9},
+	}
+
+	lines, general := annotate("", "one\ntwo\nthree\n", comments)
+
+	assert.Equal(t, len(lines), 3)
+	assert.Equal(t, len(general), 1)
+	assert.Equal(t, general[0].ID, 1)
+
+	// Both notes on lines 1 to 2 are grouped under line 2.
+	assert.Equal(t, lines[0].Noted, true)
+	assert.Equal(t, len(lines[0].Notes), 0)
+	assert.Equal(t, len(lines[1].Notes), 1)
+	assert.Equal(t, len(lines[1].Notes[0].Threads), 2)
+
+	// A note past the end of the content sticks to the last line.
+	assert.Equal(t, len(lines[2].Notes), 1)
+	assert.Equal(t, lines[2].Notes[0].Threads[0].ID, 4)
+}
+
+func TestDownloadName(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		file  models.SnippetFile
+		want  string
+	}{
+		{name: "Named file", title: "Web service", file: models.SnippetFile{Name: "Dockerfile"}, want: "Dockerfile"},
+		{name: "Title and language", title: "Docker Compose for MySQL", file: models.SnippetFile{Language: "yaml"}, want: "docker-compose-for-mysql.yaml"},
+		{name: "Plain text", title: "An old silent pond...", want: "an-old-silent-pond.txt"},
+		{name: "No usable character", title: "¿?", file: models.SnippetFile{Language: "go"}, want: "snippet.go"},
+		{name: "Long title", title: strings.Repeat("word ", 20), want: strings.TrimSuffix(strings.Repeat("word-", 10), "-") + ".txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, downloadName(tt.title, tt.file), tt.want)
+		})
+	}
+}
@@ -22,9 +22,11 @@ type application struct {
 	logger         *slog.Logger
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	comments       models.CommentModelInterface
+	tokens         models.TokenModelInterface
 	templateCache  map[string]*template.Template
 	sessionManager *scs.SessionManager
-	debug bool	
+	debug          bool
 }
 
 func main() {
@@ -63,9 +65,11 @@ func main() {
 		logger:         logger,
 		snippets:       &models.SnippetModel{DB: db},
 		users:          &models.UserModel{DB: db},
+		comments:       &models.CommentModel{DB: db},
+		tokens:         &models.TokenModel{DB: db},
 		templateCache:  template,
 		sessionManager: sessionManager,
-		debug: *debug,
+		debug:          *debug,
 	}
 
 	// init a tls.Config struct to hold then non-default TLS settings we want the server to use.
@@ -86,6 +90,8 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
+	go app.purgeExpiredComments(time.Hour)
+
 	logger.Info("Starting server", "addr", srv.Addr)
 	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
 	logger.Error(err.Error())
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/justinas/nosurf"
+	"snippetbox.hichammou/internal/models"
 )
 
 func commonHeaders(next http.Handler) http.Handler {
@@ -70,6 +73,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		if exists {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
 			r = r.WithContext(ctx)
 		}
 
@@ -78,6 +82,67 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateToken is what authenticate is to browsers for scripts: it resolves the bearer token of the Authorization
+// header to a user, and puts them in the request context the same way, along with the scope of the token. Requests
+// without a token go through anonymously, but a token that doesn't exist, was revoked or has expired is rejected
+// rather than ignored, so that the script learns about it.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		plaintext, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			app.invalidToken(w, r)
+			return
+		}
+
+		token, err := app.tokens.Authenticate(plaintext)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.invalidToken(w, r)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, token.UserID)
+		ctx = context.WithValue(ctx, tokenScopeContextKey, token.Scope)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireToken is the requireAuthentification of the routes used by scripts, which can't follow a redirection
+// to the login page. Any scope will do.
+func (app *application) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.invalidToken(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireWriteToken guards the routes that change snippets, which read tokens can't use.
+func (app *application) requireWriteToken(next http.Handler) http.Handler {
+	return app.requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scope, _ := r.Context().Value(tokenScopeContextKey).(string); scope != models.ScopeWrite {
+			app.insufficientScope(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
 func noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The OpenAPI document of the API is built from the same endpoints as its routes, and its schemas from the Go types
+// of the request and response bodies, so that the document can't drift from the code. Fields are required unless they
+// are omitted when empty or tagged openapi:"optional", pointers are nullable, and an enum tag lists the values a
+// field takes.
+
+// openAPIVersion is the version of the OpenAPI specification the document follows.
+const openAPIVersion = "3.0.3"
+
+// apiAccess is who may call an endpoint.
+type apiAccess int
+
+const (
+	// apiPublic endpoints answer anonymous clients, and clients with a token of any scope.
+	apiPublic apiAccess = iota
+	// apiToken endpoints need a token of any scope.
+	apiToken
+	// apiWriteToken endpoints need a token with the write scope.
+	apiWriteToken
+)
+
+// apiEndpoint is a route of the API, along with what its document says about it. Path is relative to /api/v1, and
+// its {name} wildcards are documented as integer path parameters.
+type apiEndpoint struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	// Description tells more than the summary, when there is more to tell.
+	Description string
+	Access      apiAccess
+	Params      []apiParam
+	// Request is a value of the type of the request body, nil when the endpoint doesn't take one.
+	Request   any
+	Responses map[int]apiResponse
+	Handler   http.HandlerFunc
+}
+
+// apiParam is a query or header parameter of an endpoint.
+type apiParam struct {
+	In          string
+	Name        string
+	Description string
+	Enum        []string
+}
+
+// apiResponse documents a status of an endpoint. Body is a value of the type of the response body, nil for none.
+type apiResponse struct {
+	Description string
+	Body        any
+}
+
+// apiOpenAPI serves the OpenAPI document of the API.
+func (app *application) apiOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := openAPIDocument(app.apiEndpoints())
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, doc)
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// openAPIDocument describes the endpoints, along with the schemas of their bodies.
+func openAPIDocument(endpoints []apiEndpoint) (map[string]any, error) {
+	schemas := openAPISchemas{}
+	paths := map[string]any{}
+
+	for _, e := range endpoints {
+		operation, err := schemas.operation(e)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %s %s: %w", e.Method, e.Path, err)
+		}
+
+		item, ok := paths[e.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[e.Path] = item
+		}
+		item[strings.ToLower(e.Method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "Snippetbox API",
+			"version": "1",
+		},
+		"servers": []any{map[string]any{"url": "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any(schemas),
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An API token, created on the API tokens page of the account. Read tokens can't change snippets.",
+				},
+			},
+		},
+	}
+
+	return doc, nil
+}
+
+// openAPISchemas holds the schemas of the named types met while describing the endpoints, by component name.
+type openAPISchemas map[string]any
+
+func (s openAPISchemas) operation(e apiEndpoint) (map[string]any, error) {
+	operation := map[string]any{
+		"operationId": e.OperationID,
+		"summary":     e.Summary,
+	}
+	if e.Description != "" {
+		operation["description"] = e.Description
+	}
+
+	var parameters []any
+	for _, segment := range strings.Split(e.Path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			parameters = append(parameters, map[string]any{
+				"name":     strings.TrimSuffix(name, "}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "integer", "minimum": 1},
+			})
+		}
+	}
+	for _, p := range e.Params {
+		schema := map[string]any{"type": "string"}
+		if len(p.Enum) > 0 {
+			schema["enum"] = p.Enum
+		}
+		parameters = append(parameters, map[string]any{
+			"name":        p.Name,
+			"in":          p.In,
+			"description": p.Description,
+			"schema":      schema,
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	// Anonymous clients are welcome on public endpoints, the empty requirement saying so.
+	switch e.Access {
+	case apiPublic:
+		operation["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []any{}}}
+	default:
+		operation["security"] = []any{map[string]any{"bearerAuth": []any{}}}
+	}
+
+	if e.Request != nil {
+		schema, err := s.schema(reflect.TypeOf(e.Request))
+		if err != nil {
+			return nil, err
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+		}
+	}
+
+	// Every endpoint rejects unknown tokens, and the ones changing snippets reject read tokens.
+	responses := map[int]apiResponse{
+		http.StatusUnauthorized: {"The token is unknown, expired or revoked, or missing where one is needed.", apiErrorResponse{}},
+	}
+	if e.Access == apiWriteToken {
+		responses[http.StatusForbidden] = apiResponse{"The token is read-only.", apiErrorResponse{}}
+	}
+	for status, response := range e.Responses {
+		if previous, ok := responses[status]; ok {
+			response.Description = previous.Description + " " + response.Description
+		}
+		responses[status] = response
+	}
+
+	documented := map[string]any{}
+	for status, response := range responses {
+		r := map[string]any{"description": response.Description}
+		if response.Body != nil {
+			schema, err := s.schema(reflect.TypeOf(response.Body))
+			if err != nil {
+				return nil, err
+			}
+			r["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+		}
+		documented[strconv.Itoa(status)] = r
+	}
+	operation["responses"] = documented
+
+	return operation, nil
+}
+
+// schema returns the schema of a type. Named structs are added to the components and referenced.
+func (s openAPISchemas) schema(t reflect.Type) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		// The siblings of a reference are ignored, so a nullable reference needs a wrapper.
+		if _, ok := schema["$ref"]; ok {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}, nil
+		}
+		schema["nullable"] = true
+		return schema, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice:
+		items, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if t == reflect.TypeFor[time.Time]() {
+			return map[string]any{"type": "string", "format": "date-time"}, nil
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+
+		name := componentName(t)
+		if _, ok := s[name]; !ok {
+			// Reserved before the fields are described, in case a type refers to itself.
+			s[name] = nil
+			object, err := s.object(t)
+			if err != nil {
+				delete(s, name)
+				return nil, err
+			}
+			s[name] = object
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// object returns the schema of a struct, with a property for each field it marshals to JSON. The fields of embedded
+// structs are promoted, like encoding/json does.
+func (s openAPISchemas) object(t reflect.Type) (map[string]any, error) {
+	properties := map[string]any{}
+	required := []string{}
+
+	err := s.fields(t, properties, &required)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema, nil
+}
+
+func (s openAPISchemas) fields(t reflect.Type, properties map[string]any, required *[]string) error {
+	for _, f := range reflect.VisibleFields(t) {
+		// Embedded structs without a JSON name are only there for the fields they promote.
+		if !f.IsExported() || f.Anonymous && f.Tag.Get("json") == "" || !promoted(t, f) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema, err := s.schema(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+
+		if enum := f.Tag.Get("enum"); enum != "" {
+			var values []any
+			for _, v := range strings.Split(enum, ",") {
+				if schema["type"] == "integer" {
+					n, err := strconv.Atoi(v)
+					if err != nil {
+						return fmt.Errorf("%s.%s: invalid enum value %q", t, f.Name, v)
+					}
+					values = append(values, n)
+				} else {
+					values = append(values, v)
+				}
+			}
+			schema["enum"] = values
+		}
+
+		properties[name] = schema
+		if !strings.Contains(options, "omitempty") && f.Tag.Get("openapi") != "optional" {
+			*required = append(*required, name)
+		}
+	}
+
+	return nil
+}
+
+// promoted reports whether a field of an embedded struct is promoted to t, which encoding/json only does for the
+// structs embedded without a JSON name.
+func promoted(t reflect.Type, f reflect.StructField) bool {
+	for i := 1; i < len(f.Index); i++ {
+		embedded := t.FieldByIndex(f.Index[:i])
+		if embedded.Tag.Get("json") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// componentName names the schema of a type after it, without the api prefix of the API types: apiSnippet is
+// Snippet.
+func componentName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
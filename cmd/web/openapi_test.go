@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+// openAPISpec is the OpenAPI document served by the test server, decoded like a client would.
+type openAPISpec struct {
+	doc map[string]any
+}
+
+func (ts *testServer) openAPI(t *testing.T) openAPISpec {
+	t.Helper()
+
+	code, _, body := ts.request(t, http.MethodGet, "/api/v1/openapi.json", nil, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/v1/openapi.json: status %d", code)
+	}
+
+	return openAPISpec{doc: decodeJSON[map[string]any](t, body)}
+}
+
+// check fails the test when a response of the API isn't the one the document describes.
+func (s openAPISpec) check(t *testing.T, method, urlPath string, code int, headers http.Header, body string) {
+	t.Helper()
+
+	for _, problem := range s.problems(method, urlPath, code, headers, body) {
+		t.Errorf("%s %s %d: %s", method, urlPath, code, problem)
+	}
+}
+
+// problems lists how a response differs from the document: its status must be documented for the operation, and its
+// body must match the schema of that status.
+func (s openAPISpec) problems(method, urlPath string, code int, headers http.Header, body string) []string {
+	operation, ok := s.operation(method, urlPath)
+	if !ok {
+		return []string{"undocumented operation"}
+	}
+
+	responses, _ := operation["responses"].(map[string]any)
+	response, ok := responses[strconv.Itoa(code)].(map[string]any)
+	if !ok {
+		return []string{"undocumented status"}
+	}
+
+	content, ok := response["content"].(map[string]any)
+	if !ok {
+		if body != "" {
+			return []string{fmt.Sprintf("no body is documented, got %q", body)}
+		}
+		return nil
+	}
+
+	mediaType, ok := content[headers.Get("Content-Type")].(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("undocumented content type %q", headers.Get("Content-Type"))}
+	}
+
+	var value any
+	err := json.Unmarshal([]byte(body), &value)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid JSON %q: %s", body, err)}
+	}
+
+	return s.validate(mediaType["schema"], value, "body")
+}
+
+// operation finds the operation of a request, matching its path against the templates of the document.
+func (s openAPISpec) operation(method, urlPath string) (map[string]any, bool) {
+	urlPath, _, _ = strings.Cut(urlPath, "?")
+	urlPath, ok := strings.CutPrefix(urlPath, "/api/v1")
+	if !ok {
+		return nil, false
+	}
+
+	paths, _ := s.doc["paths"].(map[string]any)
+	for template, item := range paths {
+		if !matchPath(template, urlPath) {
+			continue
+		}
+		operation, ok := item.(map[string]any)[strings.ToLower(method)].(map[string]any)
+		if ok {
+			return operation, true
+		}
+	}
+
+	return nil, false
+}
+
+func matchPath(template, urlPath string) bool {
+	want := strings.Split(template, "/")
+	got := strings.Split(urlPath, "/")
+	if len(want) != len(got) {
+		return false
+	}
+
+	for i := range want {
+		if !strings.HasPrefix(want[i], "{") && want[i] != got[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validate checks a decoded JSON value against the subset of the schema objects the document uses, returning what
+// doesn't match.
+func (s openAPISpec) validate(schema any, value any, path string) []string {
+	sch, ok := schema.(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("%s: invalid schema %v", path, schema)}
+	}
+
+	if ref, ok := sch["$ref"].(string); ok {
+		resolved, ok := s.resolve(ref)
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown reference %s", path, ref)}
+		}
+		return s.validate(resolved, value, path)
+	}
+
+	if value == nil {
+		if sch["nullable"] == true {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: null isn't allowed", path)}
+	}
+
+	var errs []string
+
+	if allOf, ok := sch["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			errs = append(errs, s.validate(sub, value, path)...)
+		}
+	}
+
+	if enum, ok := sch["enum"].([]any); ok && !slices.Contains(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: %v isn't one of %v", path, value, enum))
+	}
+
+	switch sch["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v isn't an object", path, value))
+		}
+
+		required, _ := sch["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing %s", path, name))
+			}
+		}
+
+		properties, _ := sch["properties"].(map[string]any)
+		for name, v := range object {
+			if property, ok := properties[name]; ok {
+				errs = append(errs, s.validate(property, v, path+"."+name)...)
+				continue
+			}
+
+			switch additional := sch["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Sprintf("%s: undocumented field %s", path, name))
+				}
+			case map[string]any:
+				errs = append(errs, s.validate(additional, v, path+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v isn't an array", path, value))
+		}
+		for i, v := range array {
+			errs = append(errs, s.validate(sch["items"], v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v isn't a string", path, value))
+		}
+		if sch["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %q isn't a date-time", path, str))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			errs = append(errs, fmt.Sprintf("%s: %v isn't an integer", path, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			errs = append(errs, fmt.Sprintf("%s: %v isn't a number", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s: %v isn't a boolean", path, value))
+		}
+	}
+
+	return errs
+}
+
+func (s openAPISpec) resolve(ref string) (any, bool) {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return nil, false
+	}
+
+	components, _ := s.doc["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	schema, ok := schemas[name]
+
+	return schema, ok
+}
+
+// refs returns every reference of a part of the document.
+func refs(v any) []string {
+	var found []string
+
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				found = append(found, ref)
+			}
+			found = append(found, refs(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			found = append(found, refs(value)...)
+		}
+	}
+
+	return found
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.request(t, http.MethodGet, "/api/v1/openapi.json", nil, "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "application/json")
+
+	spec := openAPISpec{doc: decodeJSON[map[string]any](t, body)}
+	assert.Equal(t, spec.doc["openapi"], any(openAPIVersion))
+
+	t.Run("Operations", func(t *testing.T) {
+		var operations []string
+		for path, item := range spec.doc["paths"].(map[string]any) {
+			for method, operation := range item.(map[string]any) {
+				operations = append(operations, strings.ToUpper(method)+" "+path)
+				assert.Equal(t, operation.(map[string]any)["operationId"] != "", true)
+			}
+		}
+		slices.Sort(operations)
+
+		want := []string{
+			"DELETE /snippets/{id}",
+			"GET /snippets",
+			"GET /snippets/{id}",
+			"GET /user/snippets",
+			"POST /snippets",
+			"PUT /snippets/{id}",
+		}
+		assert.Equal(t, strings.Join(operations, "\n"), strings.Join(want, "\n"))
+	})
+
+	t.Run("References", func(t *testing.T) {
+		for _, ref := range refs(spec.doc) {
+			_, ok := spec.resolve(ref)
+			assert.Equal(t, ok, true)
+		}
+	})
+
+	t.Run("Schemas", func(t *testing.T) {
+		snippet, _ := spec.resolve("#/components/schemas/Snippet")
+		properties := snippet.(map[string]any)["properties"].(map[string]any)
+
+		assert.Equal(t, properties["views_remaining"].(map[string]any)["nullable"], any(true))
+		assert.Equal(t, properties["created"].(map[string]any)["format"], any("date-time"))
+		assert.Equal(t, len(properties["visibility"].(map[string]any)["enum"].([]any)), 3)
+
+		input, _ := spec.resolve("#/components/schemas/SnippetInput")
+		assert.Equal(t, fmt.Sprint(input.(map[string]any)["required"]), "[title content]")
+
+		// The field errors of the validator are promoted to the validation errors.
+		validation, _ := spec.resolve("#/components/schemas/ValidationResponse")
+		properties = validation.(map[string]any)["properties"].(map[string]any)
+		assert.Equal(t, properties["field_errors"] != nil, true)
+		assert.Equal(t, fmt.Sprint(validation.(map[string]any)["required"]), "[error]")
+	})
+}
+
+func TestOpenAPIProblems(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	spec := ts.openAPI(t)
+
+	// Responses that drifted from the document must be caught, or the checks of the handler tests prove nothing.
+	tests := []struct {
+		name    string
+		method  string
+		urlPath string
+		code    int
+		body    string
+	}{
+		{"Undocumented operation", http.MethodPatch, "/api/v1/snippets/1", http.StatusOK, `{}`},
+		{"Undocumented status", http.MethodGet, "/api/v1/snippets/1", http.StatusTeapot, `{"error": "teapot"}`},
+		{"Missing field", http.MethodGet, "/api/v1/snippets/1", http.StatusNotFound, `{}`},
+		{"Undocumented field", http.MethodGet, "/api/v1/snippets/1", http.StatusNotFound, `{"error": "not found", "code": 404}`},
+		{"Wrong type", http.MethodPost, "/api/v1/snippets", http.StatusCreated, `{"id": "1", "url": "http://localhost/snippet/view/1"}`},
+		{"Unexpected null", http.MethodGet, "/api/v1/snippets", http.StatusOK, `{"snippets": null, "next": null, "prev": null}`},
+		{"Unexpected body", http.MethodDelete, "/api/v1/snippets/1", http.StatusNoContent, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{"Content-Type": {"application/json"}}
+
+			problems := spec.problems(tt.method, tt.urlPath, tt.code, headers, tt.body)
+			assert.Equal(t, len(problems) > 0, true)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/justinas/alice"
+	"snippetbox.hichammou/internal/models"
 	"snippetbox.hichammou/ui"
 )
 
@@ -23,7 +24,16 @@ func (app *application) routes() http.Handler {
 	mux.HandleFunc("GET /ping", ping)
 
 	mux.Handle("GET /{$}", dynamic.ThenFunc(app.Home))
+	mux.Handle("GET /snippets", dynamic.ThenFunc(app.SnippetList))
 	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.SnippetView))
+	mux.Handle("GET /snippet/view/{id}/history", dynamic.ThenFunc(app.SnippetHistory))
+	mux.Handle("GET /snippet/view/{id}/diff", dynamic.ThenFunc(app.SnippetDiff))
+	mux.Handle("POST /snippet/unlock/{id}", dynamic.ThenFunc(app.SnippetUnlockPost))
+	mux.Handle("GET /snippet/raw/{id}", dynamic.ThenFunc(app.SnippetRaw))
+	mux.Handle("GET /snippet/download/{id}", dynamic.ThenFunc(app.SnippetDownload))
+	mux.Handle("GET /tags/{tag}", dynamic.ThenFunc(app.TagView))
+	mux.Handle("GET /search", dynamic.ThenFunc(app.SearchView))
+	mux.Handle("GET /u/{user}", dynamic.ThenFunc(app.userProfile))
 	mux.Handle("GET /about", dynamic.ThenFunc(app.About))
 
 	// Add the five new routes, all of which use our 'dynamic' middleware chain.
@@ -37,12 +47,174 @@ func (app *application) routes() http.Handler {
 
 	mux.Handle("GET /snippet/create", protected.ThenFunc(app.SnippetCreate))
 	mux.Handle("POST /snippet/create", protected.ThenFunc(app.SnippetCreatePost))
+	mux.Handle("GET /snippet/fork/{id}", protected.ThenFunc(app.SnippetFork))
+	mux.Handle("POST /snippet/fork/{id}", protected.ThenFunc(app.SnippetForkPost))
+	mux.Handle("POST /snippet/star/{id}", protected.ThenFunc(app.SnippetStarPost))
+	mux.Handle("POST /snippet/comment/{id}", protected.ThenFunc(app.SnippetCommentPost))
+	mux.Handle("GET /comment/edit/{id}", protected.ThenFunc(app.CommentEdit))
+	mux.Handle("POST /comment/edit/{id}", protected.ThenFunc(app.CommentEditPost))
+	mux.Handle("POST /comment/delete/{id}", protected.ThenFunc(app.CommentDeletePost))
+	mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.SnippetEdit))
+	mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.SnippetEditPost))
+	mux.Handle("POST /snippet/delete/{id}", protected.ThenFunc(app.SnippetDeletePost))
+	mux.Handle("POST /snippet/restore/{id}/{number}", protected.ThenFunc(app.SnippetRestorePost))
 	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
 	mux.Handle("GET /user/account", protected.ThenFunc(app.Account))
+	mux.Handle("GET /user/account/tokens", protected.ThenFunc(app.userTokens))
+	mux.Handle("POST /user/account/tokens", protected.ThenFunc(app.userTokensPost))
+	mux.Handle("POST /user/account/tokens/revoke/{id}", protected.ThenFunc(app.userTokenRevokePost))
+	mux.Handle("GET /user/account/profile", protected.ThenFunc(app.userEditProfile))
+	mux.Handle("POST /user/account/profile", protected.ThenFunc(app.userEditProfilePost))
+	mux.Handle("GET /user/snippets", protected.ThenFunc(app.userSnippets))
+	mux.Handle("POST /user/snippets", protected.ThenFunc(app.userSnippetsPost))
+	mux.Handle("GET /user/stars", protected.ThenFunc(app.userStars))
 	mux.Handle("GET /user/account/change-password", protected.ThenFunc(app.userChangePassword))
 	mux.Handle("POST /user/account/change-password", protected.ThenFunc(app.userChangePasswordPost))
 
+	// Routes used by scripts authenticate with a bearer token. They have neither sessions nor CSRF tokens, which
+	// a shell can't deal with, and which aren't needed since browsers never send the token on their own.
+	token := alice.New(app.authenticateToken, app.requireWriteToken)
+
+	mux.Handle("POST /p", token.ThenFunc(app.PastePost))
+
+	mux.Handle("/api/v1/", app.apiRoutes())
+
 	standard := alice.New(app.recoverPanic, app.logRequest, commonHeaders)
 
 	return standard.Then(mux)
 }
+
+// apiRoutes returns the routes of the JSON API. Like the paste endpoint they authenticate with bearer tokens, without
+// sessions nor CSRF tokens. Reading is open to anonymous clients, with the same visibility rules as the pages.
+func (app *application) apiRoutes() http.Handler {
+	mux := http.NewServeMux()
+
+	api := alice.New(app.authenticateToken)
+	authenticated := api.Append(app.requireToken)
+	writable := api.Append(app.requireWriteToken)
+
+	for _, e := range app.apiEndpoints() {
+		chain := api
+		switch e.Access {
+		case apiToken:
+			chain = authenticated
+		case apiWriteToken:
+			chain = writable
+		}
+
+		mux.Handle(e.Method+" /api/v1"+e.Path, chain.ThenFunc(e.Handler))
+	}
+
+	mux.HandleFunc("GET /api/v1/openapi.json", app.apiOpenAPI)
+
+	return mux
+}
+
+// apiEndpoints lists the endpoints of the API, which both its routes and its OpenAPI document are made of.
+func (app *application) apiEndpoints() []apiEndpoint {
+	var (
+		badRequest = apiResponse{"The body isn't a single valid JSON object of the documented fields.", apiErrorResponse{}}
+		invalid    = apiResponse{"Some fields are invalid, field_errors telling which ones and why.", apiValidationResponse{}}
+		notFound   = apiResponse{"The snippet doesn't exist, has expired, or is private and not the token owner's.", apiErrorResponse{}}
+		notOwner   = apiResponse{"The snippet belongs to another user.", apiErrorResponse{}}
+		saved      = apiResponse{"The snippet was saved.", apiSavedResponse{}}
+		pageParams = []apiParam{
+			{In: "query", Name: "sort", Description: "What the snippets are sorted by, created by default.", Enum: []string{models.SortCreated, models.SortExpires, models.SortTitle}},
+			{In: "query", Name: "order", Description: "The order of the snippets, desc by default when sorting by creation, asc otherwise.", Enum: []string{"asc", "desc"}},
+			{In: "query", Name: "after", Description: "The next cursor of the previous page."},
+			{In: "query", Name: "before", Description: "The prev cursor of the next page."},
+		}
+		page        = apiResponse{"A page of snippets, with the cursors of the neighbouring pages.", apiSnippetsResponse{}}
+		invalidPage = apiResponse{"The sort, order or cursor parameter is invalid.", apiErrorResponse{}}
+	)
+
+	return []apiEndpoint{
+		{
+			Method:      http.MethodGet,
+			Path:        "/snippets",
+			OperationID: "listSnippets",
+			Summary:     "List the public snippets, a page at a time",
+			Access:      apiPublic,
+			Params:      pageParams,
+			Responses: map[int]apiResponse{
+				http.StatusOK:         page,
+				http.StatusBadRequest: invalidPage,
+			},
+			Handler: app.apiSnippetList,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/snippets/{id}",
+			OperationID: "getSnippet",
+			Summary:     "Get a snippet, consuming a view of the snippets with a view limit",
+			Access:      apiPublic,
+			Params: []apiParam{
+				{In: "header", Name: "X-Snippet-Passphrase", Description: "The passphrase of a protected snippet."},
+			},
+			Responses: map[int]apiResponse{
+				http.StatusOK:        {"The snippet.", apiSnippetResponse{}},
+				http.StatusForbidden: {"The snippet is protected, and the passphrase is missing or incorrect.", apiErrorResponse{}},
+				http.StatusNotFound:  notFound,
+			},
+			Handler: app.apiSnippetView,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/user/snippets",
+			OperationID: "listUserSnippets",
+			Summary:     "List the snippets of the token owner, a page at a time",
+			Access:      apiToken,
+			Params:      pageParams,
+			Responses: map[int]apiResponse{
+				http.StatusOK:         page,
+				http.StatusBadRequest: invalidPage,
+			},
+			Handler: app.apiUserSnippets,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/snippets",
+			OperationID: "createSnippet",
+			Summary:     "Create a snippet",
+			Description: "The language is detected when left out, and the snippet expires in 7 days and is public unless expires and visibility say otherwise.",
+			Access:      apiWriteToken,
+			Request:     apiSnippetInput{},
+			Responses: map[int]apiResponse{
+				http.StatusCreated:             {"The snippet was created, the Location header pointing at it.", apiSavedResponse{}},
+				http.StatusBadRequest:          badRequest,
+				http.StatusUnprocessableEntity: invalid,
+			},
+			Handler: app.apiSnippetCreate,
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/snippets/{id}",
+			OperationID: "updateSnippet",
+			Summary:     "Replace a snippet, keeping its view limit and passphrase, which can't be sent",
+			Description: "The fields left out are replaced by their defaults, except for expires and visibility: without expires the snippet keeps its expiry date, and without visibility it keeps its visibility. The language is detected when left out.",
+			Access:      apiWriteToken,
+			Request:     apiSnippetInput{},
+			Responses: map[int]apiResponse{
+				http.StatusOK:                  saved,
+				http.StatusBadRequest:          badRequest,
+				http.StatusForbidden:           notOwner,
+				http.StatusNotFound:            notFound,
+				http.StatusUnprocessableEntity: invalid,
+			},
+			Handler: app.apiSnippetUpdate,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/snippets/{id}",
+			OperationID: "deleteSnippet",
+			Summary:     "Delete a snippet",
+			Access:      apiWriteToken,
+			Responses: map[int]apiResponse{
+				http.StatusNoContent: {"The snippet was deleted.", nil},
+				http.StatusForbidden: notOwner,
+				http.StatusNotFound:  notFound,
+			},
+			Handler: app.apiSnippetDelete,
+		},
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"snippetbox.hichammou/internal/highlight"
 	"snippetbox.hichammou/internal/models"
 	"snippetbox.hichammou/ui"
 )
@@ -13,16 +14,45 @@ import (
 // Define a templateData type to act as the holding structure formaing
 // any dynamic data that we want to pass to our HTML templates.
 type templateData struct {
-	CurrentYear     int
-	Snippet         models.Snippet
-	Snippets        []models.Snippet
-	User            models.User
+	CurrentYear int
+	Snippet     models.Snippet
+	ForkOf      models.Snippet
+	Snippets    []models.Snippet
+	Revisions   []models.Revision
+	From        models.Revision
+	To          models.Revision
+	FileDiffs   []fileDiff
+	Locked      bool
+	Starred     bool
+	Comments    []models.Comment
+	Lines       []codeLine
+	// LineCount is the number of lines of the whole snippet when only a slice of it is shown, 0 otherwise.
+	LineCount     int
+	Comment       models.Comment
+	Tag           string
+	Query         string
+	Page          models.Page
+	Sort          string
+	Order         string
+	SearchResults []searchResult
+	User          models.User
+	Tokens        []models.Token
+	// Token is a newly created API token, shown to its owner this once. BaseURL is where the scripts using it connect.
+	Token           string
+	BaseURL         string
 	Form            any
 	Flash           string
 	IsAuthenticated bool
+	UserID          int
 	CSRFToken       string
 }
 
+// searchResult is a snippet found by a search, along with the extract of its content that matched.
+type searchResult struct {
+	Snippet models.Snippet
+	Excerpt template.HTML
+}
+
 func humanDate(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -30,8 +60,46 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+func sub(a, b int) int {
+	return a - b
+}
+
+// commentData is what the comment and thread partials need: the comment, and who is looking at it.
+type commentData struct {
+	models.Comment
+	SnippetID       int
+	ViewerID        int
+	IsAuthenticated bool
+	CSRFToken       string
+}
+
+func commentOf(c models.Comment, data templateData) commentData {
+	return commentData{
+		Comment:         c,
+		SnippetID:       data.Snippet.ID,
+		ViewerID:        data.UserID,
+		IsAuthenticated: data.IsAuthenticated,
+		CSRFToken:       data.CSRFToken,
+	}
+}
+
+// With returns the same data for another comment of the thread, like one of its replies.
+func (d commentData) With(c *models.Comment) commentData {
+	d.Comment = *c
+	return d
+}
+
+func languages() []highlight.Language {
+	return highlight.Languages
+}
+
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":     humanDate,
+	"sub":           sub,
+	"highlight":     highlight.HTML,
+	"languageLabel": highlight.Label,
+	"languages":     languages,
+	"commentOf":     commentOf,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
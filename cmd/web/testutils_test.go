@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,6 +36,8 @@ func newTestApplication(t *testing.T) *application {
 		templateCache:  templateCache,
 		snippets:       &mocks.SnippetModel{},
 		users:          &mocks.UserModel{},
+		comments:       &mocks.CommentModel{},
+		tokens:         &mocks.TokenModel{},
 	}
 }
 
@@ -90,6 +93,64 @@ func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, strin
 	return rs.StatusCode, rs.Header, string(body)
 }
 
+// request sends a request the way scripts call the server, with the given headers.
+func (ts *testServer) request(t *testing.T, method, urlPath string, header http.Header, body string) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(b))
+}
+
+// validToken is the write token of the mock user, and readToken their read token.
+const (
+	validToken = "MOCKTOKENFORTHEMOCKUSER"
+	readToken  = "MOCKREADTOKENFORTHEMOCKUSER"
+)
+
+// bearer returns the header authenticating a request with the token. The empty token gives no header at all.
+func bearer(token string) http.Header {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header
+}
+
+// login signs the mock user in, so the client cookie jar holds an authenticated session.
+func (ts *testServer) login(t *testing.T) {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", "hicham@gmail.com")
+	form.Add("password", "1234")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	ts.PostForm(t, "/user/login", form)
+}
+
+// csrfToken loads a page and returns the CSRF token embedded in it.
+func (ts *testServer) csrfToken(t *testing.T, urlPath string) string {
+	_, _, body := ts.get(t, urlPath)
+	return extractCSRFToken(t, body)
+}
+
 func newTestServer(t *testing.T, h http.Handler) *testServer {
 	ts := httptest.NewTLSServer(h)
 
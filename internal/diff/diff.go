@@ -0,0 +1,283 @@
+// Package diff computes line-based differences between two texts and groups them into unified diff hunks.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxEdits bounds the number of inserted and deleted lines Lines looks for. The work grows with the square of the
+// number of edits, so texts that are further apart than this aren't diffed.
+const MaxEdits = 1000
+
+// ErrTooDifferent is returned for texts that take more than MaxEdits edits to turn into one another.
+var ErrTooDifferent = errors.New("diff: the texts are too different to diff")
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the character that marks the line in a unified diff.
+func (op Op) Prefix() string {
+	switch op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of the edit script. Old and New hold the 1-based line numbers in each text,
+// or 0 when the line doesn't exist on that side.
+type Line struct {
+	Op   Op
+	Text string
+	Old  int
+	New  int
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header returns the "@@ -l,s +l,s @@" range line of the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// SplitLines splits a text into lines. Windows line endings are normalized, and a trailing newline doesn't produce an empty last line.
+func SplitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n")
+}
+
+// Lines returns the shortest edit script that turns a into b, using Myers' O(ND) algorithm. It gives up with
+// ErrTooDifferent past MaxEdits edits.
+func Lines(a, b []string) ([]Line, error) {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil, nil
+	}
+
+	// v[k+offset] holds the furthest x reached on diagonal k. Step d only reads the diagonals -d to d, so that window
+	// of v is all that is kept of every step for tracing the path back.
+	offset := total
+	v := make([]int, 2*total+2)
+	var trace [][]int
+
+	for d := 0; d <= min(total, MaxEdits); d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[k+offset] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b), nil
+			}
+		}
+	}
+
+	return nil, ErrTooDifferent
+}
+
+// furthest returns the furthest x reached on diagonal k before step d. The diagonals outside of the window saved
+// for the step hadn't been reached yet, and are still at 0.
+func furthest(trace [][]int, d, k int) int {
+	if k < -d || k > d {
+		return 0
+	}
+	return trace[d][k+d]
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var lines []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && furthest(trace, d, k-1) < furthest(trace, d, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := furthest(trace, d, prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			lines = append(lines, Line{Op: Equal, Text: a[x-1], Old: x, New: y})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, Line{Op: Insert, Text: b[y-1], New: y})
+			} else {
+				lines = append(lines, Line{Op: Delete, Text: a[x-1], Old: x})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	// The trace is walked from the end, so the lines come out reversed.
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+// Unified diffs two texts and groups the changes into hunks with the given number of context lines around them.
+func Unified(a, b string, context int) ([]Hunk, error) {
+	lines, err := Lines(SplitLines(a), SplitLines(b))
+	if err != nil {
+		return nil, err
+	}
+
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		// Skip ahead to the next change.
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start := max(i-context, 0)
+		end := i
+
+		// Extend the hunk until we find a run of unchanged lines long enough to split on.
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+
+			run := end
+			for run < len(lines) && lines[run].Op == Equal {
+				run++
+			}
+
+			if run == len(lines) || run-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, newHunk(lines, start, end))
+		i = end
+	}
+
+	return hunks, nil
+}
+
+// newHunk builds the hunk for lines[start:end], counting the lines before it to work out where it starts on each side.
+func newHunk(lines []Line, start, end int) Hunk {
+	h := Hunk{Lines: lines[start:end]}
+
+	for _, l := range lines[:start] {
+		if l.Op != Insert {
+			h.OldStart++
+		}
+		if l.Op != Delete {
+			h.NewStart++
+		}
+	}
+
+	for _, l := range h.Lines {
+		if l.Op != Insert {
+			h.OldLines++
+		}
+		if l.Op != Delete {
+			h.NewLines++
+		}
+	}
+
+	// Ranges are 1-based, except that an empty side points at the line right before the change, as GNU diff does.
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+
+	return h
+}
+
+// Format renders hunks as the text of a unified diff.
+func Format(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteString("\n")
+		for _, l := range h.Lines {
+			sb.WriteString(l.Op.Prefix())
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "Changed line",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "Added to empty",
+			a:    "",
+			b:    "one\ntwo",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			name: "Deleted everything",
+			a:    "one\n",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1 +0,0 @@\n-one\n",
+		},
+		{
+			name: "Windows line endings",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "Two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+y\n",
+		},
+		{
+			name: "Close changes share a hunk",
+			a:    "1\n2\n3\n4\n",
+			b:    "x\n2\n3\ny\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks, err := Unified(tt.a, tt.b, 1)
+			assert.NilError(t, err)
+			assert.Equal(t, Format("a", "b", hunks), tt.want)
+		})
+	}
+}
+
+func TestLinesNumbering(t *testing.T) {
+	lines, err := Lines([]string{"a", "b", "c"}, []string{"a", "c", "d"})
+	assert.NilError(t, err)
+
+	want := []Line{
+		{Op: Equal, Text: "a", Old: 1, New: 1},
+		{Op: Delete, Text: "b", Old: 2},
+		{Op: Equal, Text: "c", Old: 3, New: 2},
+		{Op: Insert, Text: "d", New: 3},
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines; want %d", len(lines), len(want))
+	}
+	for i := range want {
+		assert.Equal(t, lines[i], want[i])
+	}
+}
+
+// numbered returns n lines made unique by a prefix, so that texts with different prefixes share no line.
+func numbered(prefix string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s line %d", prefix, i)
+	}
+	return lines
+}
+
+func TestLinesLargeInputs(t *testing.T) {
+	t.Run("Too different", func(t *testing.T) {
+		a, b := numbered("old", 4000), numbered("new", 4000)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Lines(a, b)
+		runtime.ReadMemStats(&after)
+
+		assert.Equal(t, errors.Is(err, ErrTooDifferent), true)
+
+		// Only the window of v each step reads is kept, about MaxEdits² ints, where a copy of the whole of v for
+		// every step would take gigabytes.
+		allocated := after.TotalAlloc - before.TotalAlloc
+		if allocated > 64<<20 {
+			t.Errorf("allocated %d MB; want at most 64 MB", allocated>>20)
+		}
+	})
+
+	t.Run("Few edits", func(t *testing.T) {
+		a := numbered("same", 4000)
+		b := append(append(numbered("same", 2000), "inserted"), numbered("same", 4000)[2000:]...)
+
+		lines, err := Lines(a, b)
+		assert.NilError(t, err)
+		assert.Equal(t, len(lines), 4001)
+		assert.Equal(t, lines[2000], Line{Op: Insert, Text: "inserted", New: 2001})
+	})
+
+	t.Run("At the bound", func(t *testing.T) {
+		_, err := Lines(numbered("old", MaxEdits/2), numbered("new", MaxEdits/2))
+		assert.NilError(t, err)
+
+		_, err = Lines(numbered("old", MaxEdits/2+1), numbered("new", MaxEdits/2))
+		assert.Equal(t, errors.Is(err, ErrTooDifferent), true)
+	})
+}
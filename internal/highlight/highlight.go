@@ -0,0 +1,252 @@
+// Package highlight turns source code into HTML where every token is wrapped in a <span> with a CSS class.
+// It never emits inline styles, so the output works with a Content-Security-Policy that forbids them.
+package highlight
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// The CSS classes given to the tokens. Their colors live in the stylesheet.
+const (
+	Comment  = "hl-comment"
+	String   = "hl-string"
+	Number   = "hl-number"
+	Keyword  = "hl-keyword"
+	Literal  = "hl-literal"
+	Builtin  = "hl-builtin"
+	Key      = "hl-key"
+	Variable = "hl-variable"
+	Meta     = "hl-meta"
+)
+
+// Language describes a language offered in the snippet forms.
+type Language struct {
+	Name  string
+	Label string
+	// Extension is the usual file extension of the language, with its leading dot.
+	Extension string
+}
+
+// Languages lists the supported languages in the order they are offered to users.
+var Languages = []Language{
+	{Name: "go", Label: "Go", Extension: ".go"},
+	{Name: "javascript", Label: "JavaScript", Extension: ".js"},
+	{Name: "json", Label: "JSON", Extension: ".json"},
+	{Name: "python", Label: "Python", Extension: ".py"},
+	{Name: "shell", Label: "Shell", Extension: ".sh"},
+	{Name: "sql", Label: "SQL", Extension: ".sql"},
+	{Name: "yaml", Label: "YAML", Extension: ".yaml"},
+}
+
+// Supported reports whether the language name is one the highlighter knows about.
+func Supported(name string) bool {
+	_, ok := lexers[name]
+	return ok
+}
+
+// Label returns the human readable name of a language, or "Plain text" for unknown ones.
+func Label(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Label
+		}
+	}
+	return "Plain text"
+}
+
+// Extension returns the file extension of a language, or ".txt" for plain text and unknown ones.
+func Extension(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Extension
+		}
+	}
+	return ".txt"
+}
+
+type rule struct {
+	class string
+	re    *regexp.Regexp
+	// afterSpace rules only match at the start of the text or after whitespace, like shell and YAML comments.
+	afterSpace bool
+}
+
+// lexer holds the rules of one language. Rules are tried in order at every position; when none of them match,
+// identifiers are looked up in the word lists, and anything else is left as plain text.
+type lexer struct {
+	rules      []rule
+	identifier *regexp.Regexp
+	keywords   map[string]bool
+	literals   map[string]bool
+	builtins   map[string]bool
+	// ignoreCase makes the word lists case-insensitive. The lists must then be written in lower case.
+	ignoreCase bool
+}
+
+type token struct {
+	class string
+	text  string
+}
+
+// tokenize splits the source into tokens. Consecutive plain characters are merged into a single token, sliced from
+// the source once the run ends rather than grown a character at a time.
+func (l *lexer) tokenize(src string) []token {
+	var tokens []token
+
+	// start is the offset of the pending run of plain text, or -1 when there is none.
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			tokens = append(tokens, token{text: src[start:end]})
+		}
+		start = -1
+	}
+
+	pos := 0
+	for pos < len(src) {
+		rest := src[pos:]
+
+		if class, n, tail := l.match(src, pos); n > 0 {
+			flush(pos)
+			tokens = append(tokens, token{class: class, text: rest[:n]})
+			if tail > n {
+				start = pos + n
+			}
+			pos += tail
+			continue
+		}
+
+		if loc := l.identifier.FindStringIndex(rest); loc != nil {
+			word := rest[:loc[1]]
+			if class := l.classify(word); class != "" {
+				flush(pos)
+				tokens = append(tokens, token{class: class, text: word})
+			} else if start < 0 {
+				start = pos
+			}
+			pos += loc[1]
+			continue
+		}
+
+		if start < 0 {
+			start = pos
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		pos += size
+	}
+	flush(len(src))
+
+	return tokens
+}
+
+// match tries the rules at pos. It returns the class and length of the highlighted part, and the length of the whole
+// match. They differ for rules with a capture group, where only the group is highlighted.
+func (l *lexer) match(src string, pos int) (string, int, int) {
+	rest := src[pos:]
+
+	for _, r := range l.rules {
+		if r.afterSpace && pos > 0 && !strings.ContainsRune(" \t\n", rune(src[pos-1])) {
+			continue
+		}
+
+		loc := r.re.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[1] == 0 {
+			continue
+		}
+
+		if len(loc) >= 4 && loc[2] == 0 && loc[3] > 0 {
+			return r.class, loc[3], loc[1]
+		}
+		return r.class, loc[1], loc[1]
+	}
+
+	return "", 0, 0
+}
+
+func (l *lexer) classify(word string) string {
+	if l.ignoreCase {
+		word = strings.ToLower(word)
+	}
+
+	switch {
+	case l.keywords[word]:
+		return Keyword
+	case l.literals[word]:
+		return Literal
+	case l.builtins[word]:
+		return Builtin
+	default:
+		return ""
+	}
+}
+
+// splitLines normalizes line endings and splits the text into lines. A trailing newline doesn't produce an empty last line.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n")
+}
+
+// LineCount returns the number of lines Lines splits the source into.
+func LineCount(src string) int {
+	return len(splitLines(src))
+}
+
+// Lines highlights the source and returns one HTML fragment per line. Tokens that span several lines, like block comments,
+// are closed at the end of each line and reopened on the next one, so every fragment is well-formed on its own.
+// Unknown languages are escaped without any highlighting.
+func Lines(language, src string) []template.HTML {
+	lines := splitLines(src)
+	out := make([]template.HTML, 0, len(lines))
+
+	l, ok := lexers[language]
+	if !ok {
+		for _, line := range lines {
+			out = append(out, template.HTML(template.HTMLEscapeString(line)))
+		}
+		return out
+	}
+
+	var sb strings.Builder
+	for _, t := range l.tokenize(strings.Join(lines, "\n")) {
+		for i, part := range strings.Split(t.text, "\n") {
+			if i > 0 {
+				out = append(out, template.HTML(sb.String()))
+				sb.Reset()
+			}
+			if part == "" {
+				continue
+			}
+			if t.class == "" {
+				sb.WriteString(template.HTMLEscapeString(part))
+				continue
+			}
+			sb.WriteString(`<span class="` + t.class + `">`)
+			sb.WriteString(template.HTMLEscapeString(part))
+			sb.WriteString(`</span>`)
+		}
+	}
+	if len(lines) > 0 {
+		out = append(out, template.HTML(sb.String()))
+	}
+
+	return out
+}
+
+// HTML highlights the whole source as a single HTML fragment, meant to be placed inside a <pre> element.
+func HTML(language, src string) template.HTML {
+	lines := Lines(language, src)
+
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = string(line)
+	}
+
+	return template.HTML(strings.Join(parts, "\n"))
+}
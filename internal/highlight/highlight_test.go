@@ -0,0 +1,108 @@
+package highlight
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		src      string
+		want     template.HTML
+	}{
+		{
+			name:     "Plain text is escaped",
+			language: "",
+			src:      "if a < b {\n}",
+			want:     "if a &lt; b {\n}",
+		},
+		{
+			name:     "Go",
+			language: "go",
+			src:      `func main() { fmt.Println("hi", 42, nil) } // done`,
+			want:     `<span class="hl-keyword">func</span> main() { fmt.Println(<span class="hl-string">&#34;hi&#34;</span>, <span class="hl-number">42</span>, <span class="hl-literal">nil</span>) } <span class="hl-comment">// done</span>`,
+		},
+		{
+			name:     "Keywords inside identifiers",
+			language: "go",
+			src:      "format := iffy",
+			want:     "format := iffy",
+		},
+		{
+			name:     "SQL is case-insensitive",
+			language: "sql",
+			src:      "select * FROM t WHERE x IS NULL -- all",
+			want:     `<span class="hl-keyword">select</span> * <span class="hl-keyword">FROM</span> t <span class="hl-keyword">WHERE</span> x <span class="hl-keyword">IS</span> <span class="hl-literal">NULL</span> <span class="hl-comment">-- all</span>`,
+		},
+		{
+			name:     "JavaScript",
+			language: "javascript",
+			src:      "const $el = `<b>`;",
+			want:     `<span class="hl-keyword">const</span> $el = <span class="hl-string">` + "`&lt;b&gt;`" + `</span>;`,
+		},
+		{
+			name:     "Python",
+			language: "python",
+			src:      "@cache\ndef f(x):\n    return None # nope",
+			want:     "<span class=\"hl-meta\">@cache</span>\n<span class=\"hl-keyword\">def</span> f(x):\n    <span class=\"hl-keyword\">return</span> <span class=\"hl-literal\">None</span> <span class=\"hl-comment\"># nope</span>",
+		},
+		{
+			name:     "Shell",
+			language: "shell",
+			src:      "#!/bin/sh\necho \"$HOME\" ${#PATH} # home",
+			want:     "<span class=\"hl-meta\">#!/bin/sh</span>\n<span class=\"hl-builtin\">echo</span> <span class=\"hl-string\">&#34;$HOME&#34;</span> <span class=\"hl-variable\">${#PATH}</span> <span class=\"hl-comment\"># home</span>",
+		},
+		{
+			name:     "YAML",
+			language: "yaml",
+			src:      "name: web # the app\nreplicas: 3\nenabled: true",
+			want:     "<span class=\"hl-key\">name</span>: web <span class=\"hl-comment\"># the app</span>\n<span class=\"hl-key\">replicas</span>: <span class=\"hl-number\">3</span>\n<span class=\"hl-key\">enabled</span>: <span class=\"hl-literal\">true</span>",
+		},
+		{
+			name:     "JSON",
+			language: "json",
+			src:      `{"a": "b", "n": -1.5, "ok": false}`,
+			want:     `{<span class="hl-key">&#34;a&#34;</span>: <span class="hl-string">&#34;b&#34;</span>, <span class="hl-key">&#34;n&#34;</span>: <span class="hl-number">-1.5</span>, <span class="hl-key">&#34;ok&#34;</span>: <span class="hl-literal">false</span>}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HTML(tt.language, tt.src)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestLinesSplitsMultilineTokens(t *testing.T) {
+	lines := Lines("go", "/* one\r\ntwo */\nx\n")
+
+	want := []template.HTML{
+		`<span class="hl-comment">/* one</span>`,
+		`<span class="hl-comment">two */</span>`,
+		`x`,
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines; want %d", len(lines), len(want))
+	}
+	for i := range want {
+		assert.Equal(t, lines[i], want[i])
+	}
+}
+
+func TestTokenizeMergesPlainText(t *testing.T) {
+	// A long run of plain text, identifiers and punctuation, comes out as a single token.
+	src := strings.Repeat("alpha (beta) + gamma; ", 10000)
+
+	tokens := lexers["go"].tokenize(src)
+
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].class, "")
+	assert.Equal(t, tokens[0].text, src)
+}
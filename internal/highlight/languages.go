@@ -0,0 +1,143 @@
+package highlight
+
+import (
+	"regexp"
+	"strings"
+)
+
+// words turns a space separated list into a set.
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+// re compiles a rule pattern anchored at the start of the remaining text.
+func re(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(`^(?:` + pattern + `)`)
+}
+
+var (
+	identifier   = re(`[A-Za-z_][A-Za-z0-9_]*`)
+	jsIdentifier = re(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+	number        = rule{class: Number, re: re(`0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*(?:\.[0-9_]+)?(?:[eE][+-]?[0-9]+)?|\.[0-9]+(?:[eE][+-]?[0-9]+)?`)}
+	doubleQuoted  = rule{class: String, re: re(`"(?:[^"\\\n]|\\.)*"`)}
+	singleQuoted  = rule{class: String, re: re(`'(?:[^'\\\n]|\\.)*'`)}
+	slashComment  = rule{class: Comment, re: re(`//[^\n]*`)}
+	blockComment  = rule{class: Comment, re: re(`/\*[\s\S]*?(?:\*/|$)`)}
+	hashComment   = rule{class: Comment, re: re(`#[^\n]*`)}
+	spacedComment = rule{class: Comment, re: re(`#[^\n]*`), afterSpace: true}
+)
+
+var lexers = map[string]*lexer{
+	"go": {
+		rules: []rule{
+			slashComment,
+			blockComment,
+			doubleQuoted,
+			{class: String, re: re("`[^`]*`")},
+			{class: String, re: re(`'(?:[^'\\\n]|\\.)+'`)},
+			number,
+		},
+		identifier: identifier,
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if import
+			interface map package range return select struct switch type var`),
+		literals: words(`true false nil iota`),
+		builtins: words(`any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64
+			rune string uint uint8 uint16 uint32 uint64 uintptr append cap clear close complex copy delete imag len make
+			max min new panic print println real recover`),
+	},
+	"javascript": {
+		rules: []rule{
+			slashComment,
+			blockComment,
+			doubleQuoted,
+			singleQuoted,
+			{class: String, re: re("`(?:[^`\\\\]|\\\\[\\s\\S])*`")},
+			number,
+		},
+		identifier: jsIdentifier,
+		keywords: words(`async await break case catch class const continue debugger default delete do else export
+			extends finally for from function if import in instanceof let new of return static super switch this throw
+			try typeof var void while with yield`),
+		literals: words(`true false null undefined NaN Infinity`),
+		builtins: words(`Array Boolean Date Error JSON Map Math Number Object Promise RegExp Set String Symbol
+			console document window require module exports`),
+	},
+	"json": {
+		rules: []rule{
+			{class: Key, re: re(`("(?:[^"\\\n]|\\.)*")[ \t]*:`)},
+			doubleQuoted,
+			{class: Number, re: re(`-?[0-9]+(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?`)},
+		},
+		identifier: identifier,
+		literals:   words(`true false null`),
+	},
+	"python": {
+		rules: []rule{
+			hashComment,
+			{class: String, re: re(`[rRbBuUfF]{0,2}(?:"""[\s\S]*?(?:"""|$)|'''[\s\S]*?(?:'''|$))`)},
+			{class: String, re: re(`[rRbBuUfF]{0,2}(?:"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*')`)},
+			{class: Meta, re: re(`@[A-Za-z_][A-Za-z0-9_.]*`)},
+			number,
+		},
+		identifier: identifier,
+		keywords: words(`and as assert async await break class continue def del elif else except finally for from
+			global if import in is lambda nonlocal not or pass raise return try while with yield match case`),
+		literals: words(`True False None`),
+		builtins: words(`abs all any bool dict dir enumerate filter float format getattr hasattr input int isinstance
+			iter len list map max min next object open print range repr reversed round set setattr sorted str sum super
+			tuple type zip self cls`),
+	},
+	"shell": {
+		rules: []rule{
+			{class: Meta, re: re(`#![^\n]*`)},
+			spacedComment,
+			{class: String, re: re(`"(?:[^"\\]|\\[\s\S])*"`)},
+			{class: String, re: re(`'[^']*'`)},
+			{class: Variable, re: re(`\$\{[^}\n]*\}|\$[A-Za-z_][A-Za-z0-9_]*|\$[0-9@#?$!*-]`)},
+			number,
+		},
+		identifier: identifier,
+		keywords: words(`if then else elif fi for in do done case esac while until function return break continue
+			export local readonly declare select time`),
+		builtins: words(`alias bg cd command echo eval exec exit fg getopts hash jobs kill printf pwd read set shift
+			source test trap type ulimit umask unalias unset wait sudo`),
+	},
+	"sql": {
+		rules: []rule{
+			{class: Comment, re: re(`--[^\n]*`)},
+			hashComment,
+			blockComment,
+			{class: String, re: re(`'(?:[^'\\]|''|\\.)*'`)},
+			{class: Key, re: re("`[^`\n]*`")},
+			doubleQuoted,
+			number,
+		},
+		identifier: identifier,
+		keywords: words(`add all alter and any as asc auto_increment begin between by case check column commit
+			constraint create cross database default delete desc distinct drop else end exists foreign from full group
+			having if in index inner insert interval into is join key left like limit not offset on or order outer
+			primary references replace right rollback select set table then transaction truncate union unique update
+			using values view when where with`),
+		literals:   words(`null true false`),
+		builtins:   words(`avg coalesce count concat date_add ifnull lower max min now sum upper utc_timestamp integer int varchar char text datetime timestamp boolean decimal float enum`),
+		ignoreCase: true,
+	},
+	"yaml": {
+		rules: []rule{
+			spacedComment,
+			{class: Meta, re: re(`---|\.\.\.`)},
+			{class: Key, re: re(`([A-Za-z0-9_][A-Za-z0-9_.\-/]*|"[^"\n]*"|'[^'\n]*')[ \t]*:(?:[ \t]|\n|$)`)},
+			doubleQuoted,
+			{class: String, re: re(`'(?:[^'\n]|'')*'`)},
+			{class: Variable, re: re(`[&*][A-Za-z0-9_\-]+`)},
+			number,
+		},
+		identifier: identifier,
+		literals:   words(`true false yes no on off null True False Yes No On Off Null TRUE FALSE NULL`),
+	},
+}
@@ -0,0 +1,166 @@
+// Package langdetect guesses the language of a snippet from its content. It only relies on cheap heuristics: shebang lines,
+// the overall shape of the file and keywords that are typical of each language. The names it returns are the ones used by
+// the highlight package.
+package langdetect
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// minScore is the score a language needs to reach before we trust the guess. Below it the content is treated as plain text.
+const minScore = 3
+
+type signal struct {
+	re     *regexp.Regexp
+	weight int
+}
+
+// Every match of a signal adds its weight to the score of the language, up to three matches per signal
+// so that a long file repeating one construct doesn't outweigh everything else.
+var signals = map[string][]signal{
+	"go": {
+		{regexp.MustCompile(`(?m)^package \w+\s*$`), 5},
+		{regexp.MustCompile(`(?m)^import \($`), 3},
+		{regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+(\[[\w, ]+\])?\) )?\w+(\[[^\]]*\])?\(`), 3},
+		{regexp.MustCompile(`\w+ :?= range `), 2},
+		{regexp.MustCompile(`\w := `), 1},
+		{regexp.MustCompile(`\berr != nil\b`), 2},
+		{regexp.MustCompile(`\bfmt\.\w+\(`), 2},
+		{regexp.MustCompile(`(?m)^type \w+ (struct|interface) \{`), 3},
+		{regexp.MustCompile(`\bchan\b|\bgo func\(|\bdefer \w`), 2},
+	},
+	"python": {
+		{regexp.MustCompile(`(?m)^\s*def \w+\(.*\)( -> [^:]+)?:\s*$`), 3},
+		{regexp.MustCompile(`(?m)^\s*class \w+(\(.*\))?:\s*$`), 3},
+		{regexp.MustCompile(`(?m)^(from [\w.]+ )?import [\w.]+( as \w+)?\s*$`), 2},
+		{regexp.MustCompile(`(?m)^\s*(if|elif|while|for|with|try|except|else)\b.*:\s*$`), 1},
+		{regexp.MustCompile(`\bself\.\w+`), 2},
+		{regexp.MustCompile(`\b(None|True|False)\b`), 1},
+		{regexp.MustCompile(`__name__ == ['"]__main__['"]|__init__`), 3},
+		{regexp.MustCompile(`(?m)^\s*@\w+`), 1},
+		{regexp.MustCompile(`\bprint\(f?["']`), 1},
+	},
+	"javascript": {
+		{regexp.MustCompile(`(?m)^\s*(const|let|var) \w+\s*=`), 2},
+		{regexp.MustCompile(`=>`), 2},
+		{regexp.MustCompile(`\bfunction\s*\w*\s*\(`), 2},
+		{regexp.MustCompile(`\bconsole\.\w+\(`), 3},
+		{regexp.MustCompile(`\brequire\(['"]|\bmodule\.exports\b|(?m)^export (default |const |function )|(?m)^import .* from ['"]`), 3},
+		{regexp.MustCompile(`\bdocument\.|\bwindow\.|\baddEventListener\(`), 3},
+		{regexp.MustCompile(`===|!==`), 2},
+		{regexp.MustCompile(`\b(async|await|Promise)\b`), 1},
+		{regexp.MustCompile(`;\s*$`), 1},
+	},
+	"sql": {
+		{regexp.MustCompile(`(?is)\bSELECT\b.+?\bFROM\b`), 3},
+		{regexp.MustCompile(`(?i)\b(CREATE|ALTER|DROP) (TABLE|INDEX|VIEW|DATABASE)\b`), 4},
+		{regexp.MustCompile(`(?i)\bINSERT INTO\b`), 4},
+		{regexp.MustCompile(`(?i)\bUPDATE \S+ SET\b`), 4},
+		{regexp.MustCompile(`(?i)\bDELETE FROM\b`), 4},
+		{regexp.MustCompile(`(?i)\b(WHERE|GROUP BY|ORDER BY|INNER JOIN|LEFT JOIN|PRIMARY KEY|NOT NULL)\b`), 1},
+		{regexp.MustCompile(`(?m)^\s*--`), 1},
+	},
+	"shell": {
+		{regexp.MustCompile(`(?m)^\s*(if \[\[? |fi$|then$|done$|esac$|do$)`), 2},
+		{regexp.MustCompile(`\$\{\w+[^}]*\}|\$\(\w`), 2},
+		{regexp.MustCompile(`(?m)^\s*(sudo |apt-get |apt |brew |curl |wget |chmod |mkdir |cd |export |echo |source |docker |git |kubectl |systemctl |npm |go |make )`), 2},
+		{regexp.MustCompile(`\s&&\s|\s\|\|\s|\s\|\s*(grep|awk|sed|xargs|sort|head|tail|wc)\b`), 2},
+		{regexp.MustCompile(`(?m)^\s*\$ \w`), 2},
+		{regexp.MustCompile(`\s--?\w[\w-]*`), 1},
+	},
+}
+
+var shebangRX = regexp.MustCompile(`^#!\s*(?:/usr)?(?:/local)?/bin/(?:env\s+)?(\w+)`)
+
+// yamlLineRX matches the lines that make up a YAML document: mappings, list items, comments and document markers.
+var yamlLineRX = regexp.MustCompile(`^\s*(#.*|---|\.\.\.|- .*|-|[\w.\-/"']+:( .*)?)$`)
+
+// Detect returns the most likely language of the content, or an empty string when no language is convincing enough.
+func Detect(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return ""
+	}
+
+	if lang := fromShebang(trimmed); lang != "" {
+		return lang
+	}
+
+	// JSON is recognized by its shape alone: it has to parse.
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	best, bestScore := "", 0
+	for lang, score := range scores(content) {
+		if score > bestScore || (score == bestScore && lang < best) {
+			best, bestScore = lang, score
+		}
+	}
+
+	// Keywords are better evidence than the shape of the lines, but a document made only of "key: value" lines
+	// with little else going on is YAML.
+	if bestScore < 2*minScore && isYAML(trimmed) {
+		return "yaml"
+	}
+
+	if bestScore < minScore {
+		return ""
+	}
+
+	return best
+}
+
+func fromShebang(content string) string {
+	m := shebangRX.FindStringSubmatch(content)
+	if m == nil {
+		return ""
+	}
+
+	switch interpreter := m[1]; {
+	case interpreter == "sh" || interpreter == "bash" || interpreter == "zsh" || interpreter == "ksh" || interpreter == "dash":
+		return "shell"
+	case strings.HasPrefix(interpreter, "python"):
+		return "python"
+	case interpreter == "node" || interpreter == "deno":
+		return "javascript"
+	default:
+		return ""
+	}
+}
+
+func scores(content string) map[string]int {
+	result := make(map[string]int)
+
+	for lang, list := range signals {
+		for _, s := range list {
+			n := len(s.re.FindAllStringIndex(content, 3))
+			result[lang] += n * s.weight
+		}
+	}
+
+	return result
+}
+
+// isYAML reports whether at least three lines, and nearly all the non-blank lines, look like YAML.
+func isYAML(content string) bool {
+	total, matching, mappings := 0, 0, 0
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		total++
+		if yamlLineRX.MatchString(line) {
+			matching++
+			if strings.Contains(line, ":") {
+				mappings++
+			}
+		}
+	}
+
+	return mappings >= 2 && matching >= 3 && matching*10 >= total*9
+}
@@ -0,0 +1,102 @@
+package langdetect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+// minAccuracy is the share of the corpus that has to be detected correctly. Raise it as the heuristics get better.
+const minAccuracy = 0.9
+
+// TestCorpus runs Detect over every sample in testdata. Each directory is named after the language its samples
+// are written in, "text" holding the ones that should be left as plain text.
+func TestCorpus(t *testing.T) {
+	dirs, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total, correct := 0, 0
+
+	for _, dir := range dirs {
+		want := dir.Name()
+		if want == "text" {
+			want = ""
+		}
+
+		files, err := filepath.Glob(filepath.Join("testdata", dir.Name(), "*.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := Detect(string(content))
+
+			total++
+			if got == want {
+				correct++
+			} else {
+				t.Logf("%s: detected %q; want %q", file, got, want)
+			}
+		}
+	}
+
+	if total == 0 {
+		t.Fatal("the corpus is empty")
+	}
+
+	accuracy := float64(correct) / float64(total)
+	t.Logf("accuracy: %d/%d (%.1f%%)", correct, total, accuracy*100)
+
+	if accuracy < minAccuracy {
+		t.Errorf("accuracy %.2f is below the %.2f minimum", accuracy, minAccuracy)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Empty",
+			content: "  \n",
+			want:    "",
+		},
+		{
+			name:    "Python shebang",
+			content: "#!/usr/bin/env python3\nprint('hi')",
+			want:    "python",
+		},
+		{
+			name:    "Node shebang",
+			content: "#!/usr/bin/env node\nmain()",
+			want:    "javascript",
+		},
+		{
+			name:    "Invalid JSON isn't JSON",
+			content: "{ not: json",
+			want:    "",
+		},
+		{
+			name:    "Windows line endings",
+			content: "package main\r\n\r\nfunc main() {}\r\n",
+			want:    "go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Detect(tt.content), tt.want)
+		})
+	}
+}
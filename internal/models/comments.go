@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type CommentModelInterface interface {
+	Insert(snippetID, userID int, input CommentInput) (int, error)
+	Get(id int) (Comment, error)
+	ForSnippet(snippetID int) ([]Comment, error)
+	Update(id int, content string) error
+	Delete(id int) error
+	DeleteExpired() (int, error)
+}
+
+// CommentInput holds the values of a new comment.
+type CommentInput struct {
+	Content string
+	// ParentID is 0 to start a new thread, otherwise the comment is a reply. Threads are one level deep:
+	// callers should pass the top-level comment of the thread when answering a reply.
+	ParentID int
+	// LineStart and LineEnd attach a new thread to a range of lines of the snippet, they are 0 for general comments.
+	// Replies belong to the lines of their thread.
+	LineStart int
+	LineEnd   int
+}
+
+type Comment struct {
+	ID        int
+	SnippetID int
+	UserID    int
+	UserName  string
+	// ParentID isn't valid for top-level comments.
+	ParentID sql.NullInt32
+	// LineStart and LineEnd are the range of lines an inline note is attached to, both 0 for general comments.
+	LineStart int
+	LineEnd   int
+	Content   string
+	Created   time.Time
+	// Updated is only valid once the comment has been edited.
+	Updated sql.NullTime
+	// Replies holds the answers to the comment, oldest first. Only ForSnippet fills it in.
+	Replies []*Comment
+}
+
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a comment to the snippet.
+func (m *CommentModel) Insert(snippetID, userID int, input CommentInput) (int, error) {
+	var parent, lineStart, lineEnd sql.NullInt32
+	if input.ParentID > 0 {
+		parent = sql.NullInt32{Int32: int32(input.ParentID), Valid: true}
+	} else if input.LineStart > 0 {
+		lineStart = sql.NullInt32{Int32: int32(input.LineStart), Valid: true}
+		lineEnd = sql.NullInt32{Int32: int32(input.LineEnd), Valid: true}
+	}
+
+	stmt := `INSERT INTO comments (snippet_id, user_id, parent_id, line_start, line_end, content, created)
+	VALUES (?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, snippetID, userID, parent, lineStart, lineEnd, input.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// commentColumns lists the columns read by scanComment. Replies take the lines of their thread.
+const commentColumns = `c.id, c.snippet_id, c.user_id, u.name, c.parent_id,
+	COALESCE(c.line_start, p.line_start, 0), COALESCE(c.line_end, p.line_end, 0), c.content, c.created, c.updated`
+
+// commentTables joins the tables commentColumns reads from.
+const commentTables = `comments c
+	INNER JOIN users u ON u.id = c.user_id
+	LEFT JOIN comments p ON p.id = c.parent_id`
+
+func scanComment(row interface{ Scan(dest ...any) error }, c *Comment) error {
+	return row.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.UserName, &c.ParentID, &c.LineStart, &c.LineEnd, &c.Content, &c.Created, &c.Updated)
+}
+
+func (m *CommentModel) Get(id int) (Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM ` + commentTables + ` WHERE c.id = ?`
+
+	var c Comment
+	err := scanComment(m.DB.QueryRow(stmt, id), &c)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Comment{}, ErrNoRecord
+		}
+		return Comment{}, err
+	}
+
+	return c, nil
+}
+
+// ForSnippet returns the threads of the snippet, general comments and inline notes alike, oldest first, with the replies
+// nested under the comment they answer.
+func (m *CommentModel) ForSnippet(snippetID int) ([]Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM ` + commentTables + ` WHERE c.snippet_id = ? ORDER BY c.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []*Comment
+	for rows.Next() {
+		c := &Comment{}
+		err = scanComment(rows, c)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return thread(all), nil
+}
+
+// thread nests the replies under their top-level comment. Replies always come after their parent when sorted by ID,
+// so a single pass is enough.
+func thread(all []*Comment) []Comment {
+	byID := make(map[int]*Comment, len(all))
+	var roots []*Comment
+
+	for _, c := range all {
+		byID[c.ID] = c
+
+		if parent, ok := byID[int(c.ParentID.Int32)]; c.ParentID.Valid && ok {
+			parent.Replies = append(parent.Replies, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	comments := make([]Comment, len(roots))
+	for i, c := range roots {
+		comments[i] = *c
+	}
+
+	return comments
+}
+
+func (m *CommentModel) Update(id int, content string) error {
+	stmt := `UPDATE comments SET content = ?, updated = UTC_TIMESTAMP() WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, content, id)
+
+	return err
+}
+
+// Delete removes the comment along with all the replies below it.
+func (m *CommentModel) Delete(id int) error {
+	stmt := `DELETE FROM comments WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, id)
+
+	return err
+}
+
+// DeleteExpired removes the comments of the snippets that have expired or used up all their views. The comments of
+// deleted snippets go away with them through the foreign key, but expired snippets stay in the table so this has to be
+// run periodically. Only the top-level comments are deleted here, their replies follow through the foreign key.
+// It returns the number of threads removed.
+func (m *CommentModel) DeleteExpired() (int, error) {
+	stmt := `DELETE c FROM comments c
+	INNER JOIN snippets s ON s.id = c.snippet_id
+	WHERE c.parent_id IS NULL AND (s.expires <= UTC_TIMESTAMP() OR s.views_remaining <= 0)`
+
+	result, err := m.DB.Exec(stmt)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestThread(t *testing.T) {
+	reply := func(id, parent int) *Comment {
+		return &Comment{ID: id, ParentID: sql.NullInt32{Int32: int32(parent), Valid: true}}
+	}
+
+	comments := thread([]*Comment{{ID: 1}, {ID: 2}, reply(3, 1), reply(4, 2), reply(5, 1)})
+
+	assert.Equal(t, len(comments), 2)
+	assert.Equal(t, comments[0].ID, 1)
+	assert.Equal(t, len(comments[0].Replies), 2)
+	assert.Equal(t, comments[0].Replies[0].ID, 3)
+	assert.Equal(t, comments[0].Replies[1].ID, 5)
+	assert.Equal(t, comments[1].ID, 2)
+	assert.Equal(t, len(comments[1].Replies), 1)
+}
+
+func TestCommentModelDeleteExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	snippets := SnippetModel{DB: db}
+	m := CommentModel{DB: db}
+
+	id, err := snippets.Insert(1, SnippetInput{Title: "Discussed", Content: "Discussed", Expires: 7, Visibility: VisibilityPublic})
+	assert.NilError(t, err)
+
+	parent, err := m.Insert(id, 1, CommentInput{Content: "First", LineStart: 1, LineEnd: 1})
+	assert.NilError(t, err)
+
+	reply, err := m.Insert(id, 1, CommentInput{Content: "Second", ParentID: parent})
+	assert.NilError(t, err)
+
+	// A reply belongs to the lines of its thread.
+	c, err := m.Get(reply)
+	assert.NilError(t, err)
+	assert.Equal(t, c.LineStart, 1)
+
+	n, err := m.DeleteExpired()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 0)
+
+	_, err = db.Exec(`UPDATE snippets SET expires = UTC_TIMESTAMP() - INTERVAL 1 DAY WHERE id = ?`, id)
+	assert.NilError(t, err)
+
+	n, err = m.DeleteExpired()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+
+	comments, err := m.ForSnippet(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 0)
+}
@@ -8,4 +8,6 @@ var (
 	ErrInvalideCredentials = errors.New("models: invalide credentials")
 
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+
+	ErrDuplicateHandle = errors.New("models: duplicate handle")
 )
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// SnippetFile is one of the extra files of a multi-file snippet. The first file of a snippet is the snippet itself:
+// its Filename, Language and Content.
+type SnippetFile struct {
+	Name string
+	// Language is the name of the language used for syntax highlighting, empty for plain text.
+	Language string
+	Content  string
+}
+
+// File returns the file of the snippet with the given name. The empty name, like the name of the first file,
+// returns the first file.
+func (s Snippet) File(name string) (SnippetFile, bool) {
+	if name == "" || name == s.Filename {
+		return SnippetFile{Name: s.Filename, Language: s.Language, Content: s.Content}, true
+	}
+
+	for _, f := range s.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return SnippetFile{}, false
+}
+
+// setFiles replaces the extra files of a snippet, keeping them in the order of the slice.
+func setFiles(tx *sql.Tx, snippetID int, files []SnippetFile) error {
+	_, err := tx.Exec(`DELETE FROM snippet_files WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(files)*5)
+	for i, f := range files {
+		args = append(args, snippetID, i+1, f.Name, f.Language, f.Content)
+	}
+
+	stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, content) VALUES ` +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", len(files)), ", ")
+	_, err = tx.Exec(stmt, args...)
+
+	return err
+}
+
+// files returns the extra files of a single snippet in order.
+func (m *SnippetModel) files(snippetID int) ([]SnippetFile, error) {
+	stmt := `SELECT name, language, content FROM snippet_files WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var files []SnippetFile
+
+	for rows.Next() {
+		var f SnippetFile
+		err = rows.Scan(&f.Name, &f.Language, &f.Content)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, rows.Err()
+}
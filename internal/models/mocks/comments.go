@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"database/sql"
+	"time"
+
+	"snippetbox.hichammou/internal/models"
+)
+
+// mockReply is Alice's answer to mockComment.
+var mockReply = models.Comment{
+	ID:        2,
+	SnippetID: 1,
+	UserID:    2,
+	UserName:  "Alice",
+	ParentID:  sql.NullInt32{Int32: 1, Valid: true},
+	Content:   "Thanks, I wrote it last spring.",
+	Created:   time.Now(),
+}
+
+// mockComment is a comment of the mock user on mockSnippet.
+var mockComment = models.Comment{
+	ID:        1,
+	SnippetID: 1,
+	UserID:    1,
+	UserName:  "Hicham",
+	Content:   "What a lovely haiku!",
+	Created:   time.Now(),
+}
+
+// mockNote is an inline note of Alice on the only line of mockSnippet.
+var mockNote = models.Comment{
+	ID:        4,
+	SnippetID: 1,
+	UserID:    2,
+	UserName:  "Alice",
+	LineStart: 1,
+	LineEnd:   1,
+	Content:   "Shouldn't the pond be still?",
+	Created:   time.Now(),
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(snippetID, userID int, input models.CommentInput) (int, error) {
+	return 3, nil
+}
+
+func (m *CommentModel) Get(id int) (models.Comment, error) {
+	switch id {
+	case 1:
+		return mockComment, nil
+	case 2:
+		return mockReply, nil
+	case 4:
+		return mockNote, nil
+	default:
+		return models.Comment{}, models.ErrNoRecord
+	}
+}
+
+func (m *CommentModel) ForSnippet(snippetID int) ([]models.Comment, error) {
+	if snippetID == 1 {
+		c := mockComment
+		reply := mockReply
+		c.Replies = []*models.Comment{&reply}
+		return []models.Comment{c, mockNote}, nil
+	}
+	return []models.Comment{}, nil
+}
+
+func (m *CommentModel) Update(id int, content string) error {
+	return nil
+}
+
+func (m *CommentModel) Delete(id int) error {
+	return nil
+}
+
+func (m *CommentModel) DeleteExpired() (int, error) {
+	return 0, nil
+}
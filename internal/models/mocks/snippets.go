@@ -1,22 +1,140 @@
 package mocks
 
 import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"snippetbox.hichammou/internal/models"
 )
 
 var mockSnippet = models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	UserID:     1,
+	UserName:   "Hicham",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+	Visibility: models.VisibilityPublic,
+	Tags:       []string{"haiku", "poetry"},
+	Forks:      2,
+	Created:    time.Now(),
+	Expires:    time.Now().Add(7 * 24 * time.Hour),
 }
 
-type SnippetModel struct{}
+// mockForeignSnippet belongs to another user, so the mock user can see it but not change it. It is a fork of mockSnippet,
+// and the mock user starred it. It has a second file.
+var mockForeignSnippet = models.Snippet{
+	ID:         3,
+	UserID:     2,
+	UserName:   "Alice",
+	Title:      "Over the wintry forest",
+	Content:    "Over the wintry forest := true",
+	Language:   "go",
+	Filename:   "main.go",
+	Files:      []models.SnippetFile{{Name: "Dockerfile", Content: "FROM golang:1.23"}},
+	Visibility: models.VisibilityPublic,
+	ForkedFrom: sql.NullInt32{Int32: 1, Valid: true},
+	Stars:      1,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockPrivateSnippet belongs to another user and is private, so the mock user can't even see it.
+var mockPrivateSnippet = models.Snippet{
+	ID:         4,
+	UserID:     2,
+	UserName:   "Alice",
+	Title:      "Winds howl in rage",
+	Content:    "Winds howl in rage...",
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// mockBurnSnippet is burned after the first read, and it belongs to another user so reading it consumes the view.
+var mockBurnSnippet = models.Snippet{
+	ID:             5,
+	UserID:         2,
+	UserName:       "Alice",
+	Title:          "Burn after reading",
+	Content:        "This message will self-destruct",
+	Visibility:     models.VisibilityUnlisted,
+	ViewsRemaining: sql.NullInt32{Int32: 1, Valid: true},
+	Created:        time.Now(),
+	Expires:        time.Now(),
+}
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
+// mockProtectedSnippet belongs to another user and can only be read with the "open sesame" passphrase.
+var mockProtectedSnippet = models.Snippet{
+	ID:             6,
+	UserID:         2,
+	UserName:       "Alice",
+	Title:          "Behind closed doors",
+	Content:        "The treasure is under the old oak",
+	Visibility:     models.VisibilityUnlisted,
+	HashedPassword: []byte("$2a$12$hash"),
+	Created:        time.Now(),
+	Expires:        time.Now(),
+}
+
+// mockExpiredSnippet belongs to the mock user and has expired, so it only shows up in their dashboard.
+var mockExpiredSnippet = models.Snippet{
+	ID:         7,
+	UserID:     1,
+	UserName:   "Hicham",
+	Title:      "Autumn moonlight",
+	Content:    "Autumn moonlight...",
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now().Add(-48 * time.Hour),
+	Expires:    time.Now().Add(-24 * time.Hour),
+}
+
+// mockOwnProtectedSnippet belongs to the mock user and is protected, so only their dashboard lists it.
+var mockOwnProtectedSnippet = models.Snippet{
+	ID:             8,
+	UserID:         1,
+	UserName:       "Hicham",
+	Title:          "The key under the mat",
+	Content:        "The spare key is under the mat",
+	Files:          []models.SnippetFile{{Name: "alarm.txt", Content: "The alarm code is 1234"}},
+	Visibility:     models.VisibilityPublic,
+	HashedPassword: []byte("$2a$12$hash"),
+	Created:        time.Now(),
+	Expires:        time.Now().Add(7 * 24 * time.Hour),
+}
+
+// mockOwnPrivateSnippet belongs to the mock user and is private.
+var mockOwnPrivateSnippet = models.Snippet{
+	ID:         9,
+	UserID:     1,
+	UserName:   "Hicham",
+	Title:      "A hidden draft",
+	Content:    "Not ready yet",
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now(),
+	Expires:    time.Now().Add(7 * 24 * time.Hour),
+}
+
+type SnippetModel struct {
+	// burned is set once the view of mockBurnSnippet is used up. The snippet is then gone, as with the MySQL model.
+	burned atomic.Bool
+	// updated is the input of the last Update, for the tests to check what got saved.
+	updated atomic.Pointer[models.SnippetInput]
+}
+
+// LastUpdate returns the input of the last Update, and false when there was none.
+func (m *SnippetModel) LastUpdate() (models.SnippetInput, bool) {
+	input := m.updated.Load()
+	if input == nil {
+		return models.SnippetInput{}, false
+	}
+	return *input, true
+}
+
+func (m *SnippetModel) Insert(userID int, input models.SnippetInput) (int, error) {
 	return 2, nil
 }
 
@@ -24,11 +142,200 @@ func (m *SnippetModel) Get(id int) (models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 3:
+		return mockForeignSnippet, nil
+	case 4:
+		return mockPrivateSnippet, nil
+	case 5:
+		if m.burned.Load() {
+			return models.Snippet{}, models.ErrNoRecord
+		}
+		return mockBurnSnippet, nil
+	case 6:
+		return mockProtectedSnippet, nil
+	case 9:
+		return mockOwnPrivateSnippet, nil
 	default:
 		return models.Snippet{}, models.ErrNoRecord
 	}
 }
 
+func (m *SnippetModel) ConsumeView(id int) error {
+	if id == 5 && m.burned.CompareAndSwap(false, true) {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
 func (m *SnippetModel) Latest() ([]models.Snippet, error) {
 	return []models.Snippet{mockSnippet}, nil
 }
+
+// Page returns the mock snippet alone on the first page, with a cursor to an empty second page.
+func (m *SnippetModel) Page(q models.PageQuery) (models.Page, error) {
+	if q.After != nil {
+		return models.Page{Snippets: []models.Snippet{}}, nil
+	}
+	return models.Page{
+		Snippets: []models.Snippet{mockSnippet},
+		Next:     &models.Cursor{Sort: q.Sort, Key: mockSnippet.Title, ID: mockSnippet.ID},
+	}, nil
+}
+
+// ByTag pages through the mock snippet like Page for the "haiku" tag, and finds nothing for the others.
+func (m *SnippetModel) ByTag(tag string, q models.PageQuery) (models.Page, error) {
+	if tag == "haiku" {
+		return m.Page(q)
+	}
+	return models.Page{Snippets: []models.Snippet{}}, nil
+}
+
+func (m *SnippetModel) Search(q models.SearchQuery, limit int) ([]models.Snippet, error) {
+	if slices.Contains(q.Terms, "pond") || slices.Contains(q.Tags, "haiku") {
+		return []models.Snippet{mockSnippet}, nil
+	}
+	return []models.Snippet{}, nil
+}
+
+// ByUser returns the snippets of the mock user on the first page, with a cursor to an empty second page.
+func (m *SnippetModel) ByUser(userID int, q models.PageQuery) (models.Page, error) {
+	if userID != 1 || q.After != nil {
+		return models.Page{Snippets: []models.Snippet{}}, nil
+	}
+	return models.Page{
+		Snippets: []models.Snippet{mockOwnProtectedSnippet, mockSnippet, mockExpiredSnippet},
+		Next:     &models.Cursor{Sort: q.Sort, Key: mockExpiredSnippet.Title, ID: mockExpiredSnippet.ID},
+	}, nil
+}
+
+// PublicByUser pages through the mock snippet like Page for the mock user, who is the only one with snippets.
+func (m *SnippetModel) PublicByUser(userID int, q models.PageQuery) (models.Page, error) {
+	if userID == 1 {
+		return m.Page(q)
+	}
+	return models.Page{Snippets: []models.Snippet{}}, nil
+}
+
+// owned counts the IDs of mock snippets that belong to the user, as the bulk methods only change those.
+func owned(userID int, ids []int) int {
+	n := 0
+	for _, s := range []models.Snippet{mockSnippet, mockForeignSnippet, mockPrivateSnippet, mockBurnSnippet, mockProtectedSnippet, mockExpiredSnippet, mockOwnProtectedSnippet, mockOwnPrivateSnippet} {
+		if s.UserID == userID && slices.Contains(ids, s.ID) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *SnippetModel) DeleteMany(userID int, ids []int) (int, error) {
+	return owned(userID, ids), nil
+}
+
+func (m *SnippetModel) ExtendMany(userID int, ids []int, days int) (int, error) {
+	return owned(userID, ids), nil
+}
+
+func (m *SnippetModel) SetVisibilityMany(userID int, ids []int, visibility string) (int, error) {
+	return owned(userID, ids), nil
+}
+
+func (m *SnippetModel) Update(id int, input models.SnippetInput) error {
+	m.updated.Store(&input)
+	return nil
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	return nil
+}
+
+var mockRevisions = []models.Revision{
+	{
+		SnippetID: 1,
+		Number:    2,
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...",
+		Files:     []models.SnippetFile{{Name: "notes.txt", Content: "Basho, 1686"}},
+		Created:   time.Now(),
+	},
+	{
+		SnippetID: 1,
+		Number:    1,
+		Title:     "An old silent pond",
+		Content:   "An old pond...",
+		Created:   time.Now(),
+	},
+	// The revisions of the foreign snippet were rewritten from scratch, too many lines changing to diff them.
+	{
+		SnippetID: 3,
+		Number:    2,
+		Title:     "Over the wintry forest",
+		Content:   mockLines("new", 600),
+		Created:   time.Now(),
+	},
+	{
+		SnippetID: 3,
+		Number:    1,
+		Title:     "Over the wintry forest",
+		Content:   mockLines("old", 600),
+		Created:   time.Now(),
+	},
+}
+
+// mockLines returns n numbered lines, which texts with different prefixes don't share.
+func mockLines(prefix string, n int) string {
+	var sb strings.Builder
+	for i := range n {
+		fmt.Fprintf(&sb, "%s line %d\n", prefix, i)
+	}
+	return sb.String()
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]models.Revision, error) {
+	revisions := []models.Revision{}
+	for _, r := range mockRevisions {
+		if r.SnippetID == snippetID {
+			revisions = append(revisions, r)
+		}
+	}
+	return revisions, nil
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (models.Revision, error) {
+	for _, r := range mockRevisions {
+		if r.SnippetID == snippetID && r.Number == number {
+			return r, nil
+		}
+	}
+	return models.Revision{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Restore(snippetID, number int) error {
+	_, err := m.Revision(snippetID, number)
+	return err
+}
+
+func (m *SnippetModel) Unlock(id int, password string) error {
+	if id != 6 {
+		return models.ErrNoRecord
+	}
+	if password != "open sesame" {
+		return models.ErrInvalideCredentials
+	}
+	return nil
+}
+
+func (m *SnippetModel) ToggleStar(userID, snippetID int) (bool, error) {
+	starred, _ := m.IsStarred(userID, snippetID)
+	return !starred, nil
+}
+
+func (m *SnippetModel) IsStarred(userID, snippetID int) (bool, error) {
+	return userID == 1 && snippetID == 3, nil
+}
+
+func (m *SnippetModel) Starred(userID int) ([]models.Snippet, error) {
+	if userID == 1 {
+		return []models.Snippet{mockForeignSnippet}, nil
+	}
+	return []models.Snippet{}, nil
+}
@@ -0,0 +1,63 @@
+package mocks
+
+import (
+	"database/sql"
+	"time"
+
+	"snippetbox.hichammou/internal/models"
+)
+
+// mockToken authenticates the mock user with the write scope, and mockReadToken with the read scope.
+const (
+	mockToken     = "MOCKTOKENFORTHEMOCKUSER"
+	mockReadToken = "MOCKREADTOKENFORTHEMOCKUSER"
+)
+
+var mockTokenRecord = models.Token{
+	ID:       1,
+	UserID:   1,
+	Name:     "build server",
+	Scope:    models.ScopeWrite,
+	LastUsed: sql.NullTime{Time: time.Now(), Valid: true},
+	Created:  time.Now(),
+}
+
+var mockReadTokenRecord = models.Token{
+	ID:      2,
+	UserID:  1,
+	Name:    "dashboard",
+	Scope:   models.ScopeRead,
+	Expires: sql.NullTime{Time: time.Now().Add(30 * 24 * time.Hour), Valid: true},
+	Created: time.Now(),
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) Insert(userID int, input models.TokenInput) (string, error) {
+	return mockToken, nil
+}
+
+func (m *TokenModel) Authenticate(token string) (models.Token, error) {
+	switch token {
+	case mockToken:
+		return mockTokenRecord, nil
+	case mockReadToken:
+		return mockReadTokenRecord, nil
+	default:
+		return models.Token{}, models.ErrNoRecord
+	}
+}
+
+func (m *TokenModel) ForUser(userID int) ([]models.Token, error) {
+	if userID == 1 {
+		return []models.Token{mockTokenRecord, mockReadTokenRecord}, nil
+	}
+	return nil, nil
+}
+
+func (m *TokenModel) Revoke(userID, id int) error {
+	if userID == 1 && (id == 1 || id == 2) {
+		return nil
+	}
+	return models.ErrNoRecord
+}
@@ -8,10 +8,12 @@ import (
 
 type UserModel struct{}
 
-func (m *UserModel) Insert(name, email, password string) error {
-	switch email {
-	case "hicham@gmail.com":
+func (m *UserModel) Insert(name, handle, email, password string) error {
+	switch {
+	case email == "hicham@gmail.com":
 		return models.ErrDuplicateEmail
+	case handle == "hicham":
+		return models.ErrDuplicateHandle
 	default:
 		return nil
 	}
@@ -33,21 +35,35 @@ func (m *UserModel) Exists(id int) (bool, error) {
 	}
 }
 
+var mockUser = models.User{
+	ID:      1,
+	Name:    "Hicham",
+	Handle:  "hicham",
+	Email:   "hicham@example.com",
+	Bio:     "Writes haikus in Go.",
+	Created: time.Now(),
+}
+
 func (m *UserModel) Get(id int) (models.User, error) {
 	if id == 1 {
-		u := models.User{
-			ID:      1,
-			Name:    "Hicham",
-			Email:   "hicham@example.com",
-			Created: time.Now(),
-		}
-
-		return u, nil
+		return mockUser, nil
 	}
 
 	return models.User{}, models.ErrNoRecord
 }
 
+func (m *UserModel) GetByHandle(handle string) (models.User, error) {
+	if handle == "hicham" {
+		return mockUser, nil
+	}
+
+	return models.User{}, models.ErrNoRecord
+}
+
+func (m *UserModel) UpdateBio(id int, bio string) error {
+	return nil
+}
+
 func (m *UserModel) UpdatePassword(id int, oldPassword, newPassword string) error {
 	if id == 1 && oldPassword == "12345678" {
 		return nil
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// The columns a snippet listing can be sorted by.
+const (
+	SortCreated = "created"
+	SortExpires = "expires"
+	SortTitle   = "title"
+)
+
+var ErrInvalidCursor = errors.New("models: invalid cursor")
+
+// sortColumns maps a sort name to the column it orders by. Every listing breaks ties with the snippet ID,
+// so that the order is total and a cursor always points at a single row.
+var sortColumns = map[string]string{
+	SortCreated: "s.created",
+	SortExpires: "s.expires",
+	SortTitle:   "s.title",
+}
+
+// ValidSort reports whether the snippets can be sorted by the given name.
+func ValidSort(sort string) bool {
+	_, ok := sortColumns[sort]
+	return ok
+}
+
+// Cursor marks a position in a sorted listing: the sort key and the ID of the last (or first) snippet of a page.
+type Cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int    `json:"id"`
+}
+
+func cursorFor(sort string, s Snippet) Cursor {
+	c := Cursor{Sort: sort, ID: s.ID}
+
+	switch sort {
+	case SortCreated:
+		c.Key = s.Created.UTC().Format(time.RFC3339Nano)
+	case SortExpires:
+		c.Key = s.Expires.UTC().Format(time.RFC3339Nano)
+	default:
+		c.Key = s.Title
+	}
+
+	return c
+}
+
+// Encode returns the cursor as an opaque string that can be used in a URL.
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// ParseCursor decodes a string made by Encode. It returns ErrInvalidCursor when the string has been tampered with
+// or was made for another sort order.
+func ParseCursor(s, sort string) (Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.Sort != sort || c.ID < 1 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	if sort != SortTitle {
+		if _, err := time.Parse(time.RFC3339Nano, c.Key); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+
+	return c, nil
+}
+
+// key returns the sort key as the value the driver expects for the sort column.
+func (c Cursor) key() any {
+	if c.Sort == SortTitle {
+		return c.Key
+	}
+	t, _ := time.Parse(time.RFC3339Nano, c.Key)
+	return t
+}
+
+// PageQuery describes one page of a listing. At most one of After and Before is set: After asks for the page
+// following a cursor and Before for the one preceding it. With neither, the first page is returned.
+type PageQuery struct {
+	Sort   string
+	Desc   bool
+	After  *Cursor
+	Before *Cursor
+	Limit  int
+}
+
+// Page is a page of snippets, with the cursors leading to its neighbours. A nil cursor means there is no such page.
+type Page struct {
+	Snippets []Snippet
+	Next     *Cursor
+	Prev     *Cursor
+}
+
+// Page returns a page of the public snippets that haven't expired, the same ones as Latest. Like Search it leaves out
+// the protected snippets, whose content listings would otherwise give away.
+func (m *SnippetModel) Page(q PageQuery) (Page, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.expires > UTC_TIMESTAMP() AND s.visibility = 'public' AND s.views_remaining IS NULL AND s.hashed_password IS NULL`
+
+	return m.page(q, stmt)
+}
+
+// page returns a page of the snippets selected by stmt, a query ending with its WHERE clause, and its args. It uses
+// keyset pagination: instead of an OFFSET, which makes MySQL read and throw away every row before the page, the query
+// starts right after the cursor using the index of the sort column.
+func (m *SnippetModel) page(q PageQuery, stmt string, args ...any) (Page, error) {
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		return Page{}, ErrInvalidCursor
+	}
+
+	// A page before a cursor is read backwards from it, then put back in order.
+	backwards := q.Before != nil
+	desc := q.Desc != backwards
+
+	order, cmp := "ASC", ">"
+	if desc {
+		order, cmp = "DESC", "<"
+	}
+
+	cursor := q.After
+	if backwards {
+		cursor = q.Before
+	}
+	if cursor != nil {
+		if cursor.Sort != q.Sort {
+			return Page{}, ErrInvalidCursor
+		}
+		stmt += ` AND (` + column + ` ` + cmp + ` ? OR (` + column + ` = ? AND s.id ` + cmp + ` ?))`
+		args = append(args, cursor.key(), cursor.key(), cursor.ID)
+	}
+
+	// One extra row tells whether there is anything beyond this page.
+	stmt += ` ORDER BY ` + column + ` ` + order + `, s.id ` + order + ` LIMIT ?`
+	args = append(args, q.Limit+1)
+
+	snippets, err := m.list(stmt, args...)
+	if err != nil {
+		return Page{}, err
+	}
+
+	more := len(snippets) > q.Limit
+	if more {
+		snippets = snippets[:q.Limit]
+	}
+
+	if backwards {
+		for i, j := 0, len(snippets)-1; i < j; i, j = i+1, j-1 {
+			snippets[i], snippets[j] = snippets[j], snippets[i]
+		}
+	}
+
+	page := Page{Snippets: snippets}
+	if len(snippets) == 0 {
+		return page, nil
+	}
+
+	first, last := cursorFor(q.Sort, snippets[0]), cursorFor(q.Sort, snippets[len(snippets)-1])
+
+	// Coming from a cursor means there is a page on that side of it.
+	if (backwards && more) || q.After != nil {
+		page.Prev = &first
+	}
+	if (!backwards && more) || q.Before != nil {
+		page.Next = &last
+	}
+
+	return page, nil
+}
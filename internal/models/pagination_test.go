@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestParseCursor(t *testing.T) {
+	byTitle := Cursor{Sort: SortTitle, Key: "An old silent pond", ID: 1}
+	byCreated := Cursor{Sort: SortCreated, Key: "2024-01-01T10:00:00Z", ID: 2}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		sort    string
+		want    Cursor
+		wantErr error
+	}{
+		{
+			name:   "Title",
+			cursor: byTitle.Encode(),
+			sort:   SortTitle,
+			want:   byTitle,
+		},
+		{
+			name:   "Created",
+			cursor: byCreated.Encode(),
+			sort:   SortCreated,
+			want:   byCreated,
+		},
+		{
+			name:    "Other sort",
+			cursor:  byTitle.Encode(),
+			sort:    SortCreated,
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "Bad time",
+			cursor:  Cursor{Sort: SortExpires, Key: "tomorrow", ID: 1}.Encode(),
+			sort:    SortExpires,
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "Garbage",
+			cursor:  "not a cursor",
+			sort:    SortTitle,
+			wantErr: ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCursor(tt.cursor, tt.sort)
+			assert.Equal(t, err, tt.wantErr)
+			assert.Equal(t, c, tt.want)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is an immutable copy of a snippet, saved every time the snippet is created or edited. Like a snippet, its
+// Content, Language and Filename are its first file, and Files the others.
+type Revision struct {
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	Language  string
+	Filename  string
+	Files     []SnippetFile
+	Created   time.Time
+}
+
+// AllFiles returns every file of the revision, the first one included.
+func (r Revision) AllFiles() []SnippetFile {
+	first := SnippetFile{Name: r.Filename, Language: r.Language, Content: r.Content}
+	return append([]SnippetFile{first}, r.Files...)
+}
+
+// insertRevision copies the current state of the snippet, its files included, into a new revision numbered after the
+// latest one. It must run in the same transaction as the writes to snippets and snippet_files, after them, so that
+// two saves can't get the same number.
+func insertRevision(tx *sql.Tx, snippetID int) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, number, title, content, language, filename, created)
+	SELECT s.id, COALESCE((SELECT MAX(r.number) FROM snippet_revisions r WHERE r.snippet_id = s.id), 0) + 1, s.title, s.content, s.language, s.filename, UTC_TIMESTAMP()
+	FROM snippets s WHERE s.id = ?`
+
+	result, err := tx.Exec(stmt, snippetID)
+	if err != nil {
+		return err
+	}
+
+	revisionID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO revision_files (revision_id, position, name, language, content)
+	SELECT ?, position, name, language, content FROM snippet_files WHERE snippet_id = ?`
+
+	_, err = tx.Exec(stmt, revisionID, snippetID)
+
+	return err
+}
+
+// Revisions returns every revision of a snippet, newest first. They come without their extra files, which only
+// Revision reads.
+func (m *SnippetModel) Revisions(snippetID int) ([]Revision, error) {
+	stmt := `SELECT snippet_id, number, title, content, language, filename, created FROM snippet_revisions WHERE snippet_id = ? ORDER BY number DESC`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := make([]Revision, 0)
+
+	for rows.Next() {
+		var r Revision
+		err = rows.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Language, &r.Filename, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (Revision, error) {
+	stmt := `SELECT id, snippet_id, number, title, content, language, filename, created FROM snippet_revisions WHERE snippet_id = ? AND number = ?`
+
+	var revisionID int
+	var r Revision
+	err := m.DB.QueryRow(stmt, snippetID, number).Scan(&revisionID, &r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Language, &r.Filename, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrNoRecord
+		}
+		return Revision{}, err
+	}
+
+	rows, err := m.DB.Query(`SELECT name, language, content FROM revision_files WHERE revision_id = ? ORDER BY position`, revisionID)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var f SnippetFile
+		err = rows.Scan(&f.Name, &f.Language, &f.Content)
+		if err != nil {
+			return Revision{}, err
+		}
+		r.Files = append(r.Files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return Revision{}, err
+	}
+
+	return r, nil
+}
+
+// Restore brings a snippet back to one of its revisions: its title, language and every file. The restore is saved
+// as a new revision, so it can be undone like any edit. The tags, visibility and expiry are left as they are.
+func (m *SnippetModel) Restore(snippetID, number int) error {
+	r, err := m.Revision(snippetID, number)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?, filename = ? WHERE id = ?`
+
+	_, err = tx.Exec(stmt, r.Title, r.Content, r.Language, r.Filename, snippetID)
+	if err != nil {
+		return err
+	}
+
+	err = setFiles(tx, snippetID, r.Files)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, snippetID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
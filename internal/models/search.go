@@ -0,0 +1,182 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchQuery is a parsed search string. Free words end up in Terms and quoted text in Phrases, while the
+// tag:, lang: and author: filters narrow the results down without taking part in the ranking.
+type SearchQuery struct {
+	Terms     []string
+	Phrases   []string
+	Tags      []string
+	Languages []string
+	Authors   []string
+}
+
+// IsEmpty reports whether the query has nothing to search for.
+func (q SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Tags) == 0 && len(q.Languages) == 0 && len(q.Authors) == 0
+}
+
+// Words returns the terms and phrases, which are the parts of the query that can be found in the text of a snippet.
+func (q SearchQuery) Words() []string {
+	return append(append([]string{}, q.Terms...), q.Phrases...)
+}
+
+// ParseSearchQuery splits a search string like `tag:sql "inner join" lang:sql users` into its parts.
+// Filter values can be quoted too, as in author:"Alice Jones".
+func ParseSearchQuery(s string) SearchQuery {
+	var q SearchQuery
+
+	for _, token := range tokenizeQuery(s) {
+		if token.quoted {
+			q.Phrases = append(q.Phrases, token.value)
+			continue
+		}
+
+		key, value, found := strings.Cut(token.value, ":")
+		if found && value != "" {
+			switch strings.ToLower(key) {
+			case "tag":
+				q.Tags = append(q.Tags, strings.ToLower(value))
+				continue
+			case "lang", "language":
+				q.Languages = append(q.Languages, strings.ToLower(value))
+				continue
+			case "author", "by":
+				q.Authors = append(q.Authors, value)
+				continue
+			}
+		}
+
+		// Characters that have a meaning in a MySQL boolean search are dropped, so a term is always a plain word.
+		term := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`+-<>()~*"@`, r) {
+				return -1
+			}
+			return r
+		}, token.value)
+
+		if term != "" {
+			q.Terms = append(q.Terms, term)
+		}
+	}
+
+	return q
+}
+
+type queryToken struct {
+	value  string
+	quoted bool
+}
+
+// tokenizeQuery splits the query on white space, keeping quoted text together. A quote right after a filter key,
+// as in author:"Alice Jones", is part of the filter value.
+func tokenizeQuery(s string) []queryToken {
+	var tokens []queryToken
+	var current strings.Builder
+	inQuotes, quoted := false, false
+
+	flush := func() {
+		value := strings.TrimSpace(current.String())
+		if value != "" {
+			tokens = append(tokens, queryToken{value: value, quoted: quoted})
+		}
+		current.Reset()
+		quoted = false
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			if inQuotes {
+				inQuotes = false
+				flush()
+				continue
+			}
+			inQuotes = true
+			// A quote opening a whole token makes a phrase, one following "key:" is a filter value.
+			if current.Len() == 0 {
+				quoted = true
+			}
+		case unicode.IsSpace(r) && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// booleanQuery turns the terms and phrases into a MySQL boolean mode search where every word is required.
+// Terms also match as prefixes, so "migrat" finds both "migration" and "migrate".
+func (q SearchQuery) booleanQuery() string {
+	var parts []string
+
+	for _, term := range q.Terms {
+		parts = append(parts, "+"+term+"*")
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `+"`+strings.ReplaceAll(phrase, `"`, "")+`"`)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Search returns up to limit public snippets matching the query, the most relevant first. Matches in the title count
+// more than matches in the content. Like Latest it skips expired, unlisted, private and view-limited snippets, and
+// also password-protected ones whose content must not leak through the excerpts.
+func (m *SnippetModel) Search(q SearchQuery, limit int) ([]Snippet, error) {
+	var where []string
+	var args []any
+
+	where = append(where, `s.expires > UTC_TIMESTAMP()`, `s.visibility = 'public'`, `s.views_remaining IS NULL`, `s.hashed_password IS NULL`)
+
+	order := `s.created DESC, s.id DESC`
+	var orderArgs []any
+
+	if boolean := q.booleanQuery(); boolean != "" {
+		where = append(where, `MATCH(s.title, s.content) AGAINST (? IN BOOLEAN MODE)`)
+		args = append(args, boolean)
+
+		order = `(MATCH(s.title) AGAINST (? IN BOOLEAN MODE) * 2 + MATCH(s.title, s.content) AGAINST (? IN BOOLEAN MODE)) DESC, s.id DESC`
+		orderArgs = append(orderArgs, boolean, boolean)
+	}
+
+	for _, tag := range q.Tags {
+		where = append(where, `EXISTS (SELECT 1 FROM snippet_tags st INNER JOIN tags t ON t.id = st.tag_id WHERE st.snippet_id = s.id AND t.name = ?)`)
+		args = append(args, tag)
+	}
+
+	if len(q.Languages) > 0 {
+		where = append(where, `s.language IN (`+placeholders(len(q.Languages))+`)`)
+		for _, lang := range q.Languages {
+			args = append(args, lang)
+		}
+	}
+
+	// An author is either a handle or the beginning of a name.
+	for _, author := range q.Authors {
+		where = append(where, `(u.handle = ? OR u.name LIKE ?)`)
+		args = append(args, strings.ToLower(author), escapeLike(author)+"%")
+	}
+
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE ` + strings.Join(where, " AND ") + `
+	ORDER BY ` + order + ` LIMIT ?`
+
+	args = append(args, orderArgs...)
+	args = append(args, limit)
+
+	return m.list(stmt, args...)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    SearchQuery
+		boolean string
+	}{
+		{
+			name:    "Terms",
+			query:   "  inner   join ",
+			want:    SearchQuery{Terms: []string{"inner", "join"}},
+			boolean: "+inner* +join*",
+		},
+		{
+			name:    "Phrase",
+			query:   `"inner join" users`,
+			want:    SearchQuery{Terms: []string{"users"}, Phrases: []string{"inner join"}},
+			boolean: `+users* +"inner join"`,
+		},
+		{
+			name:  "Filters",
+			query: `tag:SQL lang:go author:"Alice Jones" by:bob`,
+			want: SearchQuery{
+				Tags:      []string{"sql"},
+				Languages: []string{"go"},
+				Authors:   []string{"Alice Jones", "bob"},
+			},
+			boolean: "",
+		},
+		{
+			name:    "Boolean operators are dropped",
+			query:   "-drop +table* (x)",
+			want:    SearchQuery{Terms: []string{"drop", "table", "x"}},
+			boolean: "+drop* +table* +x*",
+		},
+		{
+			name:    "Unknown filters are terms",
+			query:   "http://example.com tag:",
+			want:    SearchQuery{Terms: []string{"http://example.com", "tag:"}},
+			boolean: "+http://example.com* +tag:*",
+		},
+		{
+			name:    "Unterminated quote",
+			query:   `"select from`,
+			want:    SearchQuery{Phrases: []string{"select from"}},
+			boolean: `+"select from"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSearchQuery(tt.query)
+
+			assert.Equal(t, slices.Equal(got.Terms, tt.want.Terms), true)
+			assert.Equal(t, slices.Equal(got.Phrases, tt.want.Phrases), true)
+			assert.Equal(t, slices.Equal(got.Tags, tt.want.Tags), true)
+			assert.Equal(t, slices.Equal(got.Languages, tt.want.Languages), true)
+			assert.Equal(t, slices.Equal(got.Authors, tt.want.Authors), true)
+			assert.Equal(t, got.booleanQuery(), tt.boolean)
+		})
+	}
+}
@@ -4,30 +4,145 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A snippet's visibility decides who can find and read it. Unlisted snippets open by link but are left out of listings,
+// and private snippets can only be read by their owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
 )
 
 type SnippetModelInterface interface {
-	Insert(title string, content string, expires int) (int, error)
+	Insert(userID int, input SnippetInput) (int, error)
 	Get(id int) (Snippet, error)
+	ConsumeView(id int) error
 	Latest() ([]Snippet, error)
+	Page(q PageQuery) (Page, error)
+	ByTag(tag string, q PageQuery) (Page, error)
+	Search(q SearchQuery, limit int) ([]Snippet, error)
+	Update(id int, input SnippetInput) error
+	Delete(id int) error
+	ByUser(userID int, q PageQuery) (Page, error)
+	PublicByUser(userID int, q PageQuery) (Page, error)
+	DeleteMany(userID int, ids []int) (int, error)
+	ExtendMany(userID int, ids []int, days int) (int, error)
+	SetVisibilityMany(userID int, ids []int, visibility string) (int, error)
+	Revisions(snippetID int) ([]Revision, error)
+	Revision(snippetID, number int) (Revision, error)
+	Restore(snippetID, number int) error
+	Unlock(id int, password string) error
+	ToggleStar(userID, snippetID int) (bool, error)
+	IsStarred(userID, snippetID int) (bool, error)
+	Starred(userID int) ([]Snippet, error)
+}
+
+// KeepExpires is the Expires value of a SnippetInput that keeps the current expiry date of an updated snippet.
+const KeepExpires = 0
+
+// SnippetInput holds the values a user chooses when creating or editing a snippet.
+type SnippetInput struct {
+	Title    string
+	Content  string
+	Language string
+	// Filename is the name of the first file, it may be empty for a single-file snippet.
+	Filename string
+	// Files are the files that come after the first one.
+	Files []SnippetFile
+	// Expires is the lifetime of the snippet in days. Update leaves the expiry date as it is when it's KeepExpires.
+	Expires    int
+	Visibility string
+	Tags       []string
+	// MaxViews and Password can only be set when the snippet is created. A MaxViews of 0 means no limit,
+	// and an empty Password leaves the snippet unprotected.
+	MaxViews int
+	Password string
+	// ForkedFrom is the ID of the snippet this one was copied from, 0 for an original snippet.
+	ForkedFrom int
 }
 
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
+	ID       int
+	UserID   int
+	UserName string
+	Title    string
+	Content  string
+	// Language is the name of the language used for syntax highlighting, empty for plain text.
+	Language string
+	// Filename is the name of the first file, empty when the snippet didn't name it.
+	Filename   string
+	Visibility string
+	// ViewsRemaining isn't valid for snippets that can be read any number of times.
+	ViewsRemaining sql.NullInt32
+	// HashedPassword is nil for snippets that aren't password-protected.
+	HashedPassword []byte
+	// ForkedFrom isn't valid for original snippets, nor for forks whose original has been deleted.
+	ForkedFrom sql.NullInt32
+	// Forks is the number of live forks of the snippet and Stars the number of users who starred it.
+	// Only Get fills them in.
+	Forks int
+	Stars int
+	// Files are the files that come after the first one. Only Get fills them in.
+	Files   []SnippetFile
+	Tags    []string
 	Created time.Time
 	Expires time.Time
 }
 
+func (s Snippet) IsProtected() bool {
+	return len(s.HashedPassword) > 0
+}
+
+// IsExpired reports whether the snippet is past its expiry date or has used up all its views.
+func (s Snippet) IsExpired() bool {
+	return !s.Expires.After(time.Now()) || (s.ViewsRemaining.Valid && s.ViewsRemaining.Int32 <= 0)
+}
+
+// VisibleTo reports whether the user with the given ID may read the snippet. Pass 0 for anonymous visitors.
+func (s Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || s.UserID == userID
+}
+
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
-	stmt := `INSERT INTO snippets (title, content, created, expires) VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// Insert creates the snippet and its first revision in a single transaction.
+func (m *SnippetModel) Insert(userID int, input SnippetInput) (int, error) {
+	var viewsRemaining sql.NullInt32
+	if input.MaxViews > 0 {
+		viewsRemaining = sql.NullInt32{Int32: int32(input.MaxViews), Valid: true}
+	}
+
+	// The passphrase is hashed with the same bcrypt cost as user passwords.
+	var hashedPassword []byte
+	if input.Password != "" {
+		var err error
+		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(input.Password), 12)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback()
+
+	var forkedFrom sql.NullInt32
+	if input.ForkedFrom > 0 {
+		forkedFrom = sql.NullInt32{Int32: int32(input.ForkedFrom), Valid: true}
+	}
 
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	stmt := `INSERT INTO snippets (user_id, title, content, language, filename, visibility, views_remaining, hashed_password, forked_from, created, expires)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+
+	result, err := tx.Exec(stmt, userID, input.Title, input.Content, input.Language, input.Filename, input.Visibility, viewsRemaining, hashedPassword, forkedFrom, input.Expires)
 
 	if err != nil {
 		return 0, err
@@ -35,19 +150,51 @@ func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, nil
+		return 0, err
+	}
+
+	err = setTags(tx, int(id), input.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = setFiles(tx, int(id), input.Files)
+	if err != nil {
+		return 0, err
+	}
+
+	// The revision is taken last, once the files it copies are written.
+	err = insertRevision(tx, int(id))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
 	}
 
 	return int(id), nil
 }
 
+// snippetColumns lists the columns read by scanSnippet, in order. Queries using it alias snippets as s and join users as u.
+const snippetColumns = `s.id, s.user_id, u.name, s.title, s.content, s.language, s.filename, s.visibility, s.views_remaining, s.hashed_password, s.forked_from, s.created, s.expires`
+
+// scanSnippet maps the snippetColumns of a row to the s Snippet attributes. It accepts both *sql.Row and *sql.Rows.
+func scanSnippet(row interface{ Scan(dest ...any) error }, s *Snippet) error {
+	return row.Scan(&s.ID, &s.UserID, &s.UserName, &s.Title, &s.Content, &s.Language, &s.Filename, &s.Visibility, &s.ViewsRemaining, &s.HashedPassword, &s.ForkedFrom, &s.Created, &s.Expires)
+}
+
 func (m *SnippetModel) Get(id int) (Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	// Join the users table so the author's name comes back with the snippet.
+	// A snippet that has used up all its views is treated as expired.
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.expires > UTC_TIMESTAMP() AND (s.views_remaining IS NULL OR s.views_remaining > 0) AND s.id = ?`
 
 	var s Snippet
-	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := scanSnippet(m.DB.QueryRow(stmt, id), &s)
 
-	// This maps the returned row columns to the s Snippet attributes
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Snippet{}, ErrNoRecord
@@ -55,13 +202,64 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
 		return Snippet{}, err
 	}
 
+	s.Tags, err = m.tags(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	s.Files, err = m.files(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	stmt = `SELECT
+		(SELECT COUNT(*) FROM snippets WHERE forked_from = ? AND expires > UTC_TIMESTAMP()),
+		(SELECT COUNT(*) FROM stars WHERE snippet_id = ?)`
+
+	err = m.DB.QueryRow(stmt, s.ID, s.ID).Scan(&s.Forks, &s.Stars)
+	if err != nil {
+		return Snippet{}, err
+	}
+
 	return s, nil
 }
 
+// ConsumeView uses up one of the remaining views of a view-limited snippet. The decrement is a single conditional UPDATE,
+// so when two readers race for the last view only one of them gets a nil error, the other one gets ErrNoRecord.
+func (m *SnippetModel) ConsumeView(id int) error {
+	stmt := `UPDATE snippets SET views_remaining = views_remaining - 1
+	WHERE id = ? AND views_remaining > 0 AND expires > UTC_TIMESTAMP()`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Latest returns the 10 most recent public snippets. Unlisted and private snippets never show up here, and neither do
+// view-limited ones, so that a passer-by can't burn a one-time secret, or protected ones.
 func (m *SnippetModel) Latest() ([]Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.expires > UTC_TIMESTAMP() AND s.visibility = 'public' AND s.views_remaining IS NULL AND s.hashed_password IS NULL ORDER BY s.id DESC LIMIT 10`
+
+	return m.list(stmt)
+}
 
-	rows, err := m.DB.Query(stmt)
+// list runs a query selecting snippetColumns and returns the snippets with their tags.
+func (m *SnippetModel) list(stmt string, args ...any) ([]Snippet, error) {
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +271,7 @@ func (m *SnippetModel) Latest() ([]Snippet, error) {
 	for rows.Next() {
 		// create a new zeroed Snippet struct
 		var s Snippet
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = scanSnippet(rows, &s)
 		if err != nil {
 			return nil, err
 		}
@@ -86,6 +284,155 @@ func (m *SnippetModel) Latest() ([]Snippet, error) {
 		return nil, err
 	}
 
+	err = m.loadTags(snippets)
+	if err != nil {
+		return nil, err
+	}
+
 	// if everything went OK then return the results
 	return snippets, nil
 }
+
+// Update replaces the title and content of a snippet. Its expiry date is reset from now, unless input.Expires is
+// KeepExpires which leaves the date as it is. The previous versions are kept untouched in snippet_revisions, and the
+// new one is added next to them.
+func (m *SnippetModel) Update(id int, input SnippetInput) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = ?, content = ?, language = ?, filename = ?, visibility = ?`
+	args := []any{input.Title, input.Content, input.Language, input.Filename, input.Visibility}
+
+	if input.Expires != KeepExpires {
+		stmt += `, expires = DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY)`
+		args = append(args, input.Expires)
+	}
+
+	stmt += ` WHERE id = ?`
+	args = append(args, id)
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	err = setTags(tx, id, input.Tags)
+	if err != nil {
+		return err
+	}
+
+	err = setFiles(tx, id, input.Files)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, id)
+
+	return err
+}
+
+// ByUser returns a page of the snippets owned by the user. Unlike the public listings it includes the expired,
+// private, unlisted and used up ones, since this is what the owner manages them from.
+func (m *SnippetModel) ByUser(userID int, q PageQuery) (Page, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.user_id = ?`
+
+	return m.page(q, stmt, userID)
+}
+
+// PublicByUser returns a page of the snippets of a user that anybody can browse, with the same rules as Latest.
+func (m *SnippetModel) PublicByUser(userID int, q PageQuery) (Page, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.user_id = ? AND s.expires > UTC_TIMESTAMP() AND s.visibility = 'public' AND s.views_remaining IS NULL AND s.hashed_password IS NULL`
+
+	return m.page(q, stmt, userID)
+}
+
+// The bulk methods below only touch the snippets of the ids list that belong to userID, so a forged list can't reach
+// someone else's snippets. They return the number of snippets changed.
+
+func (m *SnippetModel) DeleteMany(userID int, ids []int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE user_id = ? AND id IN (` + placeholders(len(ids)) + `)`
+
+	return m.execMany(stmt, userID, ids)
+}
+
+// ExtendMany pushes the expiry date back by the given number of days. Expired snippets are extended from now,
+// which brings them back to life.
+func (m *SnippetModel) ExtendMany(userID int, ids []int, days int) (int, error) {
+	stmt := `UPDATE snippets SET expires = DATE_ADD(GREATEST(expires, UTC_TIMESTAMP()), INTERVAL ? DAY)
+	WHERE user_id = ? AND id IN (` + placeholders(len(ids)) + `)`
+
+	return m.execMany(stmt, userID, ids, days)
+}
+
+func (m *SnippetModel) SetVisibilityMany(userID int, ids []int, visibility string) (int, error) {
+	stmt := `UPDATE snippets SET visibility = ? WHERE user_id = ? AND id IN (` + placeholders(len(ids)) + `)`
+
+	return m.execMany(stmt, userID, ids, visibility)
+}
+
+// execMany runs a bulk statement whose arguments are the leading ones, then the user ID and finally the snippet IDs.
+func (m *SnippetModel) execMany(stmt string, userID int, ids []int, leading ...any) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := append(leading, userID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	result, err := m.DB.Exec(stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// Unlock checks the passphrase of a password-protected snippet. It returns ErrInvalideCredentials when it doesn't match.
+func (m *SnippetModel) Unlock(id int, password string) error {
+	var hashedPassword []byte
+
+	stmt := `SELECT hashed_password FROM snippets WHERE id = ? AND hashed_password IS NOT NULL`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalideCredentials
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestSnippetModelTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	id, err := m.Insert(1, SnippetInput{
+		Title:      "Tagged",
+		Content:    "SELECT 1;",
+		Language:   "sql",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+		Tags:       []string{"sql", "mysql"},
+	})
+	assert.NilError(t, err)
+
+	s, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(s.Tags), 2)
+	assert.Equal(t, s.Tags[0], "mysql")
+	assert.Equal(t, s.Tags[1], "sql")
+
+	page, err := m.ByTag("mysql", PageQuery{Sort: SortCreated, Desc: true, Limit: 20})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 1)
+
+	// Editing the snippet replaces its tags, it doesn't add to them.
+	err = m.Update(id, SnippetInput{
+		Title:      "Tagged",
+		Content:    "SELECT 2;",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+		Tags:       []string{"sql"},
+	})
+	assert.NilError(t, err)
+
+	page, err = m.ByTag("mysql", PageQuery{Sort: SortCreated, Desc: true, Limit: 20})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 0)
+}
+
+func TestSnippetModelPage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	for _, title := range []string{"Banana", "Apple", "Cherry"} {
+		_, err := m.Insert(1, SnippetInput{Title: title, Content: title, Expires: 7, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+	}
+
+	// Protected snippets are left out of the listings, even public ones.
+	_, err := m.Insert(1, SnippetInput{Title: "Avocado", Content: "Avocado", Expires: 7, Visibility: VisibilityPublic, Password: "open sesame"})
+	assert.NilError(t, err)
+
+	page, err := m.Page(PageQuery{Sort: SortTitle, Limit: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 2)
+	assert.Equal(t, page.Snippets[0].Title, "Apple")
+	assert.Equal(t, page.Snippets[1].Title, "Banana")
+	assert.Equal(t, page.Prev == nil, true)
+
+	page, err = m.Page(PageQuery{Sort: SortTitle, After: page.Next, Limit: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 1)
+	assert.Equal(t, page.Snippets[0].Title, "Cherry")
+	assert.Equal(t, page.Next == nil, true)
+
+	// Going back from the last page returns the first one, in the same order.
+	page, err = m.Page(PageQuery{Sort: SortTitle, Before: page.Prev, Limit: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 2)
+	assert.Equal(t, page.Snippets[0].Title, "Apple")
+	assert.Equal(t, page.Prev == nil, true)
+}
+
+func TestSnippetModelBulk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	var ids []int
+	for _, title := range []string{"First", "Second"} {
+		id, err := m.Insert(1, SnippetInput{Title: title, Content: title, Expires: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+		ids = append(ids, id)
+	}
+
+	// Another user can't touch them.
+	n, err := m.SetVisibilityMany(2, ids, VisibilityPrivate)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 0)
+
+	n, err = m.SetVisibilityMany(1, ids, VisibilityPrivate)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 2)
+
+	page, err := m.ByUser(1, PageQuery{Sort: SortCreated, Desc: true, Limit: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 1)
+	assert.Equal(t, page.Snippets[0].Visibility, VisibilityPrivate)
+
+	page, err = m.ByUser(1, PageQuery{Sort: SortCreated, Desc: true, After: page.Next, Limit: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(page.Snippets), 1)
+	assert.Equal(t, page.Next == nil, true)
+
+	n, err = m.DeleteMany(1, ids[:1])
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+}
+
+func TestSnippetModelUpdateExpires(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	id, err := m.Insert(1, SnippetInput{Title: "First", Content: "First", Expires: 1, Visibility: VisibilityPublic})
+	assert.NilError(t, err)
+
+	before, err := m.Get(id)
+	assert.NilError(t, err)
+
+	err = m.Update(id, SnippetInput{Title: "Second", Content: "Second", Expires: KeepExpires, Visibility: VisibilityPublic})
+	assert.NilError(t, err)
+
+	s, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Title, "Second")
+	assert.Equal(t, s.Expires.Equal(before.Expires), true)
+
+	err = m.Update(id, SnippetInput{Title: "Third", Content: "Third", Expires: 365, Visibility: VisibilityPublic})
+	assert.NilError(t, err)
+
+	s, err = m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Expires.After(before.Expires), true)
+}
+
+func TestSnippetModelStars(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	id, err := m.Insert(1, SnippetInput{Title: "Starred", Content: "Starred", Expires: 7, Visibility: VisibilityPublic})
+	assert.NilError(t, err)
+
+	starred, err := m.ToggleStar(1, id)
+	assert.NilError(t, err)
+	assert.Equal(t, starred, true)
+
+	s, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Stars, 1)
+
+	snippets, err := m.Starred(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippets), 1)
+
+	// Once expired, the snippet leaves the list.
+	_, err = db.Exec(`UPDATE snippets SET expires = UTC_TIMESTAMP() - INTERVAL 1 DAY WHERE id = ?`, id)
+	assert.NilError(t, err)
+
+	snippets, err = m.Starred(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippets), 0)
+
+	starred, err = m.ToggleStar(1, id)
+	assert.NilError(t, err)
+	assert.Equal(t, starred, false)
+}
+
+func TestSnippetModelFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	id, err := m.Insert(1, SnippetInput{
+		Title:      "Web service",
+		Content:    "FROM golang:1.23",
+		Filename:   "Dockerfile",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+		Files: []SnippetFile{
+			{Name: "config.yaml", Language: "yaml", Content: "port: 4000"},
+			{Name: "main.go", Language: "go", Content: "package main"},
+		},
+	})
+	assert.NilError(t, err)
+
+	s, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Filename, "Dockerfile")
+	assert.Equal(t, len(s.Files), 2)
+	assert.Equal(t, s.Files[0].Name, "config.yaml")
+	assert.Equal(t, s.Files[1].Language, "go")
+
+	// Editing the snippet replaces its files.
+	err = m.Update(id, SnippetInput{
+		Title:      "Web service",
+		Content:    "FROM golang:1.23",
+		Filename:   "Dockerfile",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+		Files:      []SnippetFile{{Name: "main.go", Language: "go", Content: "package main"}},
+	})
+	assert.NilError(t, err)
+
+	s, err = m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(s.Files), 1)
+	assert.Equal(t, s.Files[0].Name, "main.go")
+}
+
+func TestSnippetModelRevisions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	id, err := m.Insert(1, SnippetInput{
+		Title:      "Web service",
+		Content:    "FROM golang:1.23",
+		Filename:   "Dockerfile",
+		Language:   "dockerfile",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+		Files: []SnippetFile{
+			{Name: "config.yaml", Language: "yaml", Content: "port: 4000"},
+			{Name: "main.go", Language: "go", Content: "package main"},
+		},
+	})
+	assert.NilError(t, err)
+
+	err = m.Update(id, SnippetInput{
+		Title:      "Web service",
+		Content:    "port: 8080",
+		Filename:   "config.yaml",
+		Language:   "yaml",
+		Expires:    7,
+		Visibility: VisibilityPublic,
+	})
+	assert.NilError(t, err)
+
+	// Every file and the language are versioned, not only the first file.
+	first, err := m.Revision(id, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, first.Filename, "Dockerfile")
+	assert.Equal(t, first.Language, "dockerfile")
+	assert.Equal(t, len(first.Files), 2)
+	assert.Equal(t, first.Files[1].Content, "package main")
+
+	second, err := m.Revision(id, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, second.Filename, "config.yaml")
+	assert.Equal(t, len(second.Files), 0)
+
+	err = m.Restore(id, 1)
+	assert.NilError(t, err)
+
+	s, err := m.Get(id)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Content, "FROM golang:1.23")
+	assert.Equal(t, s.Filename, "Dockerfile")
+	assert.Equal(t, s.Language, "dockerfile")
+	assert.Equal(t, len(s.Files), 2)
+	assert.Equal(t, s.Files[0].Name, "config.yaml")
+	assert.Equal(t, s.Files[1].Language, "go")
+
+	// The restore is a revision of its own.
+	revisions, err := m.Revisions(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(revisions), 3)
+
+	third, err := m.Revision(id, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, len(third.Files), 2)
+
+	err = m.Restore(id, 9)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+}
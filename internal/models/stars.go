@@ -0,0 +1,52 @@
+package models
+
+// ToggleStar stars the snippet for the user, or removes the star when there already is one.
+// It returns whether the snippet is starred afterwards.
+func (m *SnippetModel) ToggleStar(userID, snippetID int) (bool, error) {
+	result, err := m.DB.Exec(`DELETE FROM stars WHERE user_id = ? AND snippet_id = ?`, userID, snippetID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if rows > 0 {
+		return false, nil
+	}
+
+	// INSERT IGNORE keeps a double click from failing on the primary key.
+	stmt := `INSERT IGNORE INTO stars (user_id, snippet_id, created) VALUES (?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(stmt, userID, snippetID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m *SnippetModel) IsStarred(userID, snippetID int) (bool, error) {
+	var starred bool
+
+	stmt := `SELECT EXISTS (SELECT true FROM stars WHERE user_id = ? AND snippet_id = ?)`
+	err := m.DB.QueryRow(stmt, userID, snippetID).Scan(&starred)
+
+	return starred, err
+}
+
+// Starred returns the snippets starred by the user, the most recently starred first. The stars of expired or used up
+// snippets are kept but the snippets are left out, just like Get doesn't return them, and so are the snippets that
+// were made private by their owner since.
+func (m *SnippetModel) Starred(userID int) ([]Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM stars st
+	INNER JOIN snippets s ON s.id = st.snippet_id
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE st.user_id = ? AND s.expires > UTC_TIMESTAMP() AND (s.views_remaining IS NULL OR s.views_remaining > 0)
+	AND (s.visibility <> 'private' OR s.user_id = st.user_id)
+	ORDER BY st.created DESC, s.id DESC`
+
+	return m.list(stmt, userID)
+}
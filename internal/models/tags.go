@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// placeholders returns n comma separated "?" for an IN (...) clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// setTags replaces the tags of a snippet. Tags that don't exist yet are created on the way.
+func setTags(tx *sql.Tx, snippetID int, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+
+	// INSERT IGNORE skips the tags that already exist because of the unique constraint on name.
+	stmt := `INSERT IGNORE INTO tags (name) VALUES ` + strings.TrimSuffix(strings.Repeat("(?), ", len(tags)), ", ")
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name IN (` + placeholders(len(tags)) + `)`
+	_, err = tx.Exec(stmt, append([]any{snippetID}, args...)...)
+
+	return err
+}
+
+// tags returns the tags of a single snippet in alphabetical order.
+func (m *SnippetModel) tags(snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t INNER JOIN snippet_tags st ON st.tag_id = t.id WHERE st.snippet_id = ? ORDER BY t.name`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tags []string
+
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// loadTags fills the Tags of every snippet in the slice with a single query.
+func (m *SnippetModel) loadTags(snippets []Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(snippets))
+	index := make(map[int][]int)
+	for i, s := range snippets {
+		args[i] = s.ID
+		index[s.ID] = append(index[s.ID], i)
+	}
+
+	stmt := `SELECT st.snippet_id, t.name FROM tags t INNER JOIN snippet_tags st ON st.tag_id = t.id
+	WHERE st.snippet_id IN (` + placeholders(len(snippets)) + `) ORDER BY t.name`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var tag string
+		err = rows.Scan(&id, &tag)
+		if err != nil {
+			return err
+		}
+		for _, i := range index[id] {
+			snippets[i].Tags = append(snippets[i].Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// ByTag returns a page of the public snippets carrying the tag. Like Latest it leaves out unlisted, private,
+// view-limited and protected snippets.
+func (m *SnippetModel) ByTag(tag string, q PageQuery) (Page, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	INNER JOIN snippet_tags st ON st.snippet_id = s.id
+	INNER JOIN tags t ON t.id = st.tag_id
+	WHERE t.name = ? AND s.expires > UTC_TIMESTAMP() AND s.visibility = 'public' AND s.views_remaining IS NULL AND s.hashed_password IS NULL`
+
+	return m.page(q, stmt, tag)
+}
@@ -1,26 +1,140 @@
+CREATE TABLE users (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    handle VARCHAR(30) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_password CHAR(60) NOT NULL,
+    bio TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_uc_handle UNIQUE (handle);
+
 CREATE TABLE snippets (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
     title VARCHAR(100) NOT NULL,
     content TEXT NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    filename VARCHAR(100) NOT NULL DEFAULT '',
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    views_remaining INTEGER NULL,
+    hashed_password CHAR(60) NULL,
+    forked_from INTEGER NULL,
     created DATETIME NOT NULL,
-    expires DATETIME NOT NULL
+    expires DATETIME NOT NULL,
+    CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    -- Forks outlive the snippet they were copied from.
+    CONSTRAINT snippets_fk_forked_from FOREIGN KEY (forked_from) REFERENCES snippets(id) ON DELETE SET NULL
 );
 
 CREATE INDEX idx_snippets_created ON snippets(created);
+CREATE INDEX idx_snippets_expires ON snippets(expires);
+CREATE INDEX idx_snippets_title ON snippets(title);
 
-CREATE TABLE users (
+-- The title has an index of its own so that title matches can be ranked higher.
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets(title, content);
+CREATE FULLTEXT INDEX idx_snippets_fulltext_title ON snippets(title);
+
+CREATE TABLE tags (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
-    name VARCHAR(255) NOT NULL,
-    email VARCHAR(255) NOT NULL,
-    hashed_password CHAR(60) NOT NULL,
-    created DATETIME NOT NULL
+    name VARCHAR(30) NOT NULL,
+    CONSTRAINT tags_uc_name UNIQUE (name)
 );
 
-ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id),
+    CONSTRAINT snippet_tags_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT snippet_tags_fk_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE TABLE snippet_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    number INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    filename VARCHAR(100) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_number UNIQUE (snippet_id, number),
+    CONSTRAINT snippet_revisions_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+-- The content of a snippet is its first file, the other files of a multi-file snippet are kept here in order.
+CREATE TABLE snippet_files (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    CONSTRAINT snippet_files_uc_position UNIQUE (snippet_id, position),
+    CONSTRAINT snippet_files_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+-- The extra files of a snippet as they were at a revision, like snippet_files.
+CREATE TABLE revision_files (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    revision_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    CONSTRAINT revision_files_uc_position UNIQUE (revision_id, position),
+    CONSTRAINT revision_files_fk_revision FOREIGN KEY (revision_id) REFERENCES snippet_revisions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE stars (
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id),
+    CONSTRAINT stars_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT stars_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_stars_snippet ON stars(snippet_id);
+
+CREATE TABLE comments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
+    -- Inline notes are attached to a range of lines, general comments leave both columns NULL.
+    line_start INTEGER NULL,
+    line_end INTEGER NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    updated DATETIME NULL,
+    CONSTRAINT comments_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT comments_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT comments_fk_parent FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+-- Tokens authenticate scripts. Only a SHA-256 hash of each token is stored.
+CREATE TABLE tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    scope ENUM('read', 'write') NOT NULL DEFAULT 'write',
+    created DATETIME NOT NULL,
+    -- Tokens that never expire have a NULL expiry date.
+    expires DATETIME NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash),
+    CONSTRAINT tokens_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
 
-INSERT INTO users (name, email, hashed_password, created) VALUES (
+INSERT INTO users (name, handle, email, hashed_password, bio, created) VALUES (
     'Alice Jones',
+    'alice',
     'alice@example.com',
     '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
+    '',
     '2022-01-01 09:18:24'
 );
@@ -1,3 +1,19 @@
-DROP TABLE users;
+DROP TABLE tokens;
+
+DROP TABLE comments;
+
+DROP TABLE stars;
+
+DROP TABLE snippet_tags;
+
+DROP TABLE tags;
+
+DROP TABLE snippet_files;
+
+DROP TABLE revision_files;
+
+DROP TABLE snippet_revisions;
 
 DROP TABLE snippets;
+
+DROP TABLE users;
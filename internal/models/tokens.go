@@ -0,0 +1,163 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// A token's scope decides what a script can do with it. Read tokens can only fetch snippets, including the private ones
+// of their owner, write tokens can also create, change and delete them.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+type TokenModelInterface interface {
+	Insert(userID int, input TokenInput) (string, error)
+	Authenticate(token string) (Token, error)
+	ForUser(userID int) ([]Token, error)
+	Revoke(userID, id int) error
+}
+
+// TokenInput holds the values a user chooses when creating a token. An Expires of 0 means the token never expires.
+type TokenInput struct {
+	Name    string
+	Scope   string
+	Expires int
+}
+
+type Token struct {
+	ID     int
+	UserID int
+	Name   string
+	Scope  string
+	// Expires isn't valid for tokens that never expire, and LastUsed for tokens that haven't been used yet.
+	Expires  sql.NullTime
+	LastUsed sql.NullTime
+	Created  time.Time
+}
+
+// IsExpired reports whether the token is past its expiry date.
+func (t Token) IsExpired() bool {
+	return t.Expires.Valid && !t.Expires.Time.After(time.Now())
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// hashToken returns the hash stored in place of a token. Tokens are long random strings rather than passwords a user
+// picked, so a fast hash is enough: there is nothing to guess from it, and lookups can go through an index.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Insert creates a token for the user and returns it. Only its hash is kept, so this is the one time the token can be
+// read.
+func (m *TokenModel) Insert(userID int, input TokenInput) (string, error) {
+	random := make([]byte, 20)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+
+	token := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(random)
+
+	var expires sql.NullInt32
+	if input.Expires > 0 {
+		expires = sql.NullInt32{Int32: int32(input.Expires), Valid: true}
+	}
+
+	// DATE_ADD of a NULL interval is NULL, which is how tokens that never expire are stored.
+	stmt := `INSERT INTO tokens (user_id, name, hash, scope, created, expires)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+
+	_, err = m.DB.Exec(stmt, userID, input.Name, hashToken(token), input.Scope, expires)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+const tokenColumns = `id, user_id, name, scope, expires, last_used, created`
+
+func scanToken(row interface{ Scan(dest ...any) error }, t *Token) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Expires, &t.LastUsed, &t.Created)
+}
+
+// Authenticate returns the token, or ErrNoRecord when it doesn't exist, was revoked or has expired. It records the use
+// of the token, at most once a minute so that a busy script doesn't turn every request into a write.
+func (m *TokenModel) Authenticate(token string) (Token, error) {
+	stmt := `SELECT ` + tokenColumns + ` FROM tokens
+	WHERE hash = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+
+	var t Token
+	err := scanToken(m.DB.QueryRow(stmt, hashToken(token)), &t)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrNoRecord
+		}
+		return Token{}, err
+	}
+
+	stmt = `UPDATE tokens SET last_used = UTC_TIMESTAMP()
+	WHERE id = ? AND (last_used IS NULL OR last_used < UTC_TIMESTAMP() - INTERVAL 1 MINUTE)`
+
+	_, err = m.DB.Exec(stmt, t.ID)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return t, nil
+}
+
+// ForUser returns the tokens of the user, newest first, expired ones included so that they can be cleaned up.
+func (m *TokenModel) ForUser(userID int) ([]Token, error) {
+	stmt := `SELECT ` + tokenColumns + ` FROM tokens WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []Token
+
+	for rows.Next() {
+		var t Token
+		err = scanToken(rows, &t)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Revoke deletes a token of the user. It returns ErrNoRecord when the user has no token with that ID.
+func (m *TokenModel) Revoke(userID, id int) error {
+	result, err := m.DB.Exec(`DELETE FROM tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
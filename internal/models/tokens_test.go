@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+
+	"snippetbox.hichammou/internal/assert"
+)
+
+func TestTokenModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := TokenModel{DB: db}
+
+	token, err := m.Insert(1, TokenInput{Name: "laptop", Scope: ScopeRead})
+	assert.NilError(t, err)
+	assert.Equal(t, len(token), 32)
+
+	tok, err := m.Authenticate(token)
+	assert.NilError(t, err)
+	assert.Equal(t, tok.UserID, 1)
+	assert.Equal(t, tok.Scope, ScopeRead)
+	assert.Equal(t, tok.Expires.Valid, false)
+
+	// The token itself is never stored.
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM tokens WHERE hash = ?`, token).Scan(&count)
+	assert.NilError(t, err)
+	assert.Equal(t, count, 0)
+
+	tokens, err := m.ForUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].LastUsed.Valid, true)
+
+	_, err = m.Authenticate("not-a-token")
+	assert.Equal(t, err, ErrNoRecord)
+
+	// Expired tokens are rejected.
+	_, err = db.Exec(`UPDATE tokens SET expires = UTC_TIMESTAMP() - INTERVAL 1 DAY WHERE id = ?`, tok.ID)
+	assert.NilError(t, err)
+
+	_, err = m.Authenticate(token)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// Only the owner can revoke a token.
+	err = m.Revoke(2, tok.ID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	err = m.Revoke(1, tok.ID)
+	assert.NilError(t, err)
+
+	tokens, err = m.ForUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokens), 0)
+}
@@ -11,18 +11,23 @@ import (
 )
 
 type UserModelInterface interface {
-	Insert(name, email, password string) error
+	Insert(name, handle, email, password string) error
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
 	Get(id int) (User, error)
+	GetByHandle(handle string) (User, error)
+	UpdateBio(id int, bio string) error
 	UpdatePassword(id int, oldPassword, newPassword string) error
 }
 
 type User struct {
-	ID             int
-	Name           string
+	ID   int
+	Name string
+	// Handle is the unique name used in profile URLs, like /u/alice.
+	Handle         string
 	Email          string
 	HashedPassword []byte
+	Bio            string
 	Created        time.Time
 }
 
@@ -30,15 +35,15 @@ type UserModel struct {
 	DB *sql.DB
 }
 
-func (m *UserModel) Insert(name, email, password string) error {
+func (m *UserModel) Insert(name, handle, email, password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
 		return err
 	}
 
-	stmt := `INSERT INTO users (name, email, hashed_password, created) VALUES (?, ?, ?, UTC_TIMESTAMP())`
+	stmt := `INSERT INTO users (name, handle, email, hashed_password, bio, created) VALUES (?, ?, ?, ?, '', UTC_TIMESTAMP())`
 
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	_, err = m.DB.Exec(stmt, name, handle, email, string(hashedPassword))
 
 	if err != nil {
 		// If this returns an error, we use the errors.As() function to check whether the error has the type *mysql.MySQLError. If it does, the error will
@@ -50,6 +55,9 @@ func (m *UserModel) Insert(name, email, password string) error {
 			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
 				return ErrDuplicateEmail
 			}
+			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_handle") {
+				return ErrDuplicateHandle
+			}
 		}
 
 		return err
@@ -96,10 +104,17 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 }
 
 func (m *UserModel) Get(id int) (User, error) {
+	return m.get(`SELECT id, name, handle, email, bio, created FROM users WHERE id = ?`, id)
+}
+
+func (m *UserModel) GetByHandle(handle string) (User, error) {
+	return m.get(`SELECT id, name, handle, email, bio, created FROM users WHERE handle = ?`, handle)
+}
+
+func (m *UserModel) get(stmt string, arg any) (User, error) {
 	var u User
-	stmt := `SELECT id, name, email, created FROM users WHERE id = ?`
 
-	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.Created)
+	err := m.DB.QueryRow(stmt, arg).Scan(&u.ID, &u.Name, &u.Handle, &u.Email, &u.Bio, &u.Created)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return u, ErrNoRecord
@@ -110,6 +125,14 @@ func (m *UserModel) Get(id int) (User, error) {
 	return u, nil
 }
 
+func (m *UserModel) UpdateBio(id int, bio string) error {
+	stmt := `UPDATE users SET bio = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, bio, id)
+
+	return err
+}
+
 func (m *UserModel) UpdatePassword(id int, oldPassword, newPassword string) error {
 	var password []byte
 
@@ -47,3 +47,23 @@ func TestUserModelExist(t *testing.T) {
 		})
 	}
 }
+
+func TestUserModelGetByHandle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	m := UserModel{db}
+
+	u, err := m.GetByHandle("alice")
+	assert.NilError(t, err)
+	assert.Equal(t, u.ID, 1)
+	assert.Equal(t, u.Name, "Alice Jones")
+
+	_, err = m.GetByHandle("bob")
+	assert.Equal(t, err, ErrNoRecord)
+
+	err = m.Insert("Alice Smith", "alice", "smith@example.com", "pa$$word")
+	assert.Equal(t, err, ErrDuplicateHandle)
+}
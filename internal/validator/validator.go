@@ -1,17 +1,25 @@
 package validator
 
 import (
+	"cmp"
 	"regexp"
 	"slices"
 	"strings"
 	"unicode/utf8"
 )
 
+// TagRX matches a single tag: lower case letters, digits and a few separators, starting with a letter or a digit.
+var TagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]*$`)
+
+// HandleRX matches a user handle. It starts with a letter so it can't be mistaken for a user ID in profile URLs.
+var HandleRX = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// The JSON names are the ones the API reports validation errors with.
 type Validator struct {
-	NonFieldErrors []string
-	FieldErrors    map[string]string
+	NonFieldErrors []string          `json:"non_field_errors,omitempty"`
+	FieldErrors    map[string]string `json:"field_errors,omitempty"`
 }
 
 func (v *Validator) Valid() bool {
@@ -46,6 +54,11 @@ func MaxChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) <= n
 }
 
+// MaxBytes() returns true if a value doesn't take more than n bytes, which is what a database column limits.
+func MaxBytes(value string, n int) bool {
+	return len(value) <= n
+}
+
 func MinChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) >= n
 }
@@ -62,3 +75,33 @@ func Equals[T comparable](v1, v2 T) bool {
 func PermittedValue[T comparable](value T, PermittedValues ...T) bool {
 	return slices.Contains(PermittedValues, value)
 }
+
+// Between() returns true if a value is within the inclusive range [min, max].
+func Between[T cmp.Ordered](value, min, max T) bool {
+	return value >= min && value <= max
+}
+
+// MaxItems() returns true if a list doesn't hold more than n values.
+func MaxItems[T any](values []T, n int) bool {
+	return len(values) <= n
+}
+
+// AllMaxChars() returns true if none of the values is longer than n characters.
+func AllMaxChars(values []string, n int) bool {
+	for _, value := range values {
+		if !MaxChars(value, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllMatch() returns true if every value matches the regular expression.
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, value := range values {
+		if !Match(value, rx) {
+			return false
+		}
+	}
+	return true
+}
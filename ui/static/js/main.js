@@ -5,4 +5,84 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Highlight the lines named in the URL fragment of a snippet page, like #L5 or #L5-L12. A single line is also
+// highlighted by the :target rule of the stylesheet, this adds ranges, which CSS can't express.
+var lineRangeRX = /^#L(\d+)(?:-L(\d+))?$/;
+
+function highlightLines() {
+	var selected = document.querySelectorAll("table.code tr.selected");
+	for (var i = 0; i < selected.length; i++) {
+		selected[i].classList.remove("selected");
+	}
+
+	var match = lineRangeRX.exec(window.location.hash);
+	if (!match) {
+		return;
+	}
+
+	var start = parseInt(match[1], 10);
+	var end = match[2] ? parseInt(match[2], 10) : start;
+
+	var first = null;
+	for (var n = start; n <= end; n++) {
+		var row = document.getElementById("L" + n);
+		if (row) {
+			row.classList.add("selected");
+			first = first || row;
+		}
+	}
+
+	if (first) {
+		first.scrollIntoView();
+	}
+}
+
+// Shift-clicking a line number extends the selection from the line selected before, like code hosts do.
+var gutterLinks = document.querySelectorAll("table.code td.gutter a");
+for (var i = 0; i < gutterLinks.length; i++) {
+	gutterLinks[i].addEventListener("click", function (event) {
+		var current = lineRangeRX.exec(window.location.hash);
+		var clicked = lineRangeRX.exec(this.getAttribute("href"));
+		if (!event.shiftKey || !current || !clicked) {
+			return;
+		}
+
+		event.preventDefault();
+		var a = parseInt(current[1], 10);
+		var b = parseInt(clicked[1], 10);
+		history.replaceState(null, "", "#L" + Math.min(a, b) + "-L" + Math.max(a, b));
+		highlightLines();
+	});
+}
+
+window.addEventListener("hashchange", highlightLines);
+highlightLines();
+
+// Without JavaScript the "Add a file" button posts the form back to get one more file. With it, the file is added
+// on the spot from the template of the create form, and the remove buttons become available.
+var files = document.getElementById("files");
+var fileTemplate = document.getElementById("file-template");
+var addFile = document.querySelector("input.add-file");
+
+if (files && fileTemplate && addFile) {
+	var removeButtons = files.querySelectorAll("button.remove-file");
+	for (var i = 0; i < removeButtons.length; i++) {
+		removeButtons[i].hidden = false;
+	}
+
+	addFile.addEventListener("click", function (event) {
+		event.preventDefault();
+		var file = fileTemplate.content.firstElementChild.cloneNode(true);
+		file.querySelector("button.remove-file").hidden = false;
+		files.appendChild(file);
+		file.querySelector("input").focus();
+	});
+
+	files.addEventListener("click", function (event) {
+		if (event.target.classList.contains("remove-file")) {
+			event.target.closest("fieldset.file").remove();
+		}
+	});
+}